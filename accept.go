@@ -0,0 +1,129 @@
+package cosnet
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// acceptRejectWriteTimeout 发送拒绝错误包的最长时间
+const acceptRejectWriteTimeout = time.Second
+
+// acceptRejectLogInterval 拒绝日志的最小间隔，间隔内的拒绝只计数，在下一条日志中输出数量
+const acceptRejectLogInterval = time.Second
+
+// AcceptHook 准入检查函数，在创建 Socket 之前调用，返回错误时拒绝连接。
+// 可以根据远程地址、TLS 状态（conn 实现了 listener.TLSConn 接口时）、监听器、当前连接数（Sockets.Count）等拒绝连接，
+// 例如维护模式、地区屏蔽。返回 *Error 时错误码会随拒绝错误包发送给对端。
+type AcceptHook func(conn listener.Conn, ln listener.Listener) error
+
+// RejectError 连接被拒绝的原因，EventTypeRejected 事件的参数。
+type RejectError struct {
+	Address  net.Addr          // 对端地址
+	Listener listener.Listener // 接受连接的监听器
+	Err      error             // 准入检查返回的错误
+}
+
+// Error 实现 error 接口。
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("connection %v rejected:%v", e.Address, e.Err)
+}
+
+// Unwrap 返回准入检查返回的错误。
+func (e *RejectError) Unwrap() error {
+	return e.Err
+}
+
+// OnAccept 添加准入检查（初始化时使用），按添加顺序调用，任意一个返回错误时拒绝连接。
+// 准入检查在每个连接独立的协程中执行，较慢的检查和拒绝包不会阻塞监听器接受其它连接。
+// 被拒绝的连接会被计数（Sockets.Rejected）、记录 Alert 日志（每秒最多一条）并触发 EventTypeRejected 事件，
+// 设置了 Options.AcceptRejectPath 时先向对端发送错误包再关闭连接。
+// 参数 hook: 准入检查函数。
+func (ss *Sockets) OnAccept(hook ...AcceptHook) {
+	ss.acceptHooks = append(ss.acceptHooks, hook...)
+}
+
+// Rejected 被准入检查拒绝的连接总数。
+func (ss *Sockets) Rejected() int64 {
+	return atomic.LoadInt64(&ss.rejected)
+}
+
+// admitAndCreate 执行准入检查，通过后创建 Socket
+func (ss *Sockets) admitAndCreate(conn listener.Conn, ln listener.Listener) {
+	if !ss.admit(conn, ln) {
+		return
+	}
+	if _, err := ss.create(conn, "", ln, nil); err != nil {
+		logger.Debug("listener.Accept Error:%v", err)
+	}
+}
+
+// admit 执行准入检查，拒绝时关闭连接并返回 false
+func (ss *Sockets) admit(conn listener.Conn, ln listener.Listener) bool {
+	var err error
+	for _, hook := range ss.acceptHooks {
+		if err = ss.acceptHook(hook, conn, ln); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		return true
+	}
+	atomic.AddInt64(&ss.rejected, 1)
+	re := &RejectError{Address: conn.RemoteAddr(), Listener: ln, Err: err}
+	ss.logReject(re)
+	ss.Emit(EventTypeRejected, nil, re)
+	if path := ss.Options.AcceptRejectPath; path != "" {
+		if e := ss.reject(conn, ln, path, err); e != nil {
+			logger.Debug("connection %v reject reply error:%v", re.Address, e)
+		}
+	}
+	_ = conn.Close()
+	return false
+}
+
+// logReject 记录拒绝原因，每 acceptRejectLogInterval 最多一条，避免大量连接被拒绝时刷屏
+func (ss *Sockets) logReject(re *RejectError) {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&ss.rejectLog)
+	if now-last < int64(acceptRejectLogInterval) || !atomic.CompareAndSwapInt64(&ss.rejectLog, last, now) {
+		atomic.AddInt64(&ss.rejectSkip, 1)
+		return
+	}
+	if n := atomic.SwapInt64(&ss.rejectSkip, 0); n > 0 {
+		logger.Alert("%v,%d more rejected since last log", re, n)
+	} else {
+		logger.Alert("%v", re)
+	}
+}
+
+// acceptHook 调用准入检查，panic 视为拒绝
+func (ss *Sockets) acceptHook(hook AcceptHook, conn listener.Conn, ln listener.Listener) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("accept hook panic:%v", e)
+		}
+	}()
+	return hook(conn, ln)
+}
+
+// reject 向对端发送拒绝错误包
+func (ss *Sockets) reject(conn listener.Conn, ln listener.Listener, path string, err error) error {
+	data, e := ErrorBinder.Marshal(ToError(err))
+	if e != nil {
+		return e
+	}
+	codec := ss.listenerCodec(ln)
+	m := message.Require(codec)
+	defer message.Release(m)
+	if e = m.Marshal(codec.DefaultMagic(), message.FlagConfirm|message.FlagError, 0, path, data); e != nil {
+		return e
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(acceptRejectWriteTimeout))
+	return conn.WriteMessage(nil, m)
+}
@@ -0,0 +1,80 @@
+package cosnet
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+func TestAcceptHookNotBlocking(t *testing.T) {
+	var count int32
+	gate := make(chan struct{})
+	defer close(gate)
+	_, address := testServer(t, func(ss *Sockets) {
+		ss.OnAccept(func(conn listener.Conn, ln listener.Listener) error {
+			// 第一个连接的准入检查一直阻塞
+			if atomic.AddInt32(&count, 1) == 1 {
+				<-gate
+			}
+			return nil
+		})
+		_ = ss.Service().Register(func(c *Context) any {
+			return []byte("pong")
+		}, "/ping")
+	})
+	testClient(t, address)
+	testEventually(t, testTimeout, func() bool {
+		return atomic.LoadInt32(&count) == 1
+	})
+	sock, replies := testClient(t, address)
+	_ = sock.Send(0, 1, "/ping", nil)
+	if r := testReceive(t, replies); string(r.body) != "pong" {
+		t.Fatalf("reply %+v", r)
+	}
+}
+
+func TestAcceptReject(t *testing.T) {
+	rejected := make(chan *RejectError, 1)
+	ss, address := testServer(t, func(ss *Sockets) {
+		ss.Options.AcceptRejectPath = "/rejected"
+		ss.OnAccept(func(conn listener.Conn, ln listener.Listener) error {
+			return NewError(ErrorCodeOverload, "maintenance")
+		})
+		ss.On(EventTypeRejected, func(_ *Socket, v any) {
+			rejected <- v.(*RejectError)
+		})
+	})
+	_, replies := testClient(t, address)
+	r := testReceive(t, replies)
+	if e := testError(t, r); e == nil || e.Code != ErrorCodeOverload || r.path != "/rejected" {
+		t.Fatalf("reject reply %+v", r)
+	}
+	re := <-rejected
+	var e *Error
+	if !errors.As(re, &e) || e.Message != "maintenance" || ss.Rejected() != 1 {
+		t.Fatalf("reject error %v,rejected:%d", re, ss.Rejected())
+	}
+	if ss.Count() != 0 {
+		t.Fatalf("rejected connection created socket,count:%d", ss.Count())
+	}
+}
+
+// 拒绝日志每秒最多一条，期间的拒绝计数后在下一条日志中输出
+func TestRejectLogLimit(t *testing.T) {
+	ss := New()
+	re := &RejectError{Err: errors.New("maintenance")}
+	for i := 0; i < 5; i++ {
+		ss.logReject(re)
+	}
+	if n := atomic.LoadInt64(&ss.rejectSkip); n != 4 {
+		t.Fatalf("skipped %d", n)
+	}
+	// 超过间隔后记录日志并清零
+	atomic.AddInt64(&ss.rejectLog, -int64(acceptRejectLogInterval))
+	ss.logReject(re)
+	if n := atomic.LoadInt64(&ss.rejectSkip); n != 0 {
+		t.Fatalf("skipped %d after interval", n)
+	}
+}
@@ -0,0 +1,366 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hwcer/cosgo/registry"
+	"github.com/hwcer/cosgo/values"
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+)
+
+// New 创建连接管理 HTTP 接口。
+// 返回的 Handler 实现了 http.Handler，可以挂载到任意路径，例如：
+//
+//	http.Handle("/admin/", http.StripPrefix("/admin", admin.New(cosnet.Default)))
+//
+// 接口列表:
+//   - GET  /sockets              连接列表，支持 user、ip(IP 或 CIDR)、state、age(秒)、limit 过滤
+//   - GET  /sockets/{id}         单个连接详情
+//   - POST /sockets/{id}/kick    踢下线，参数 reason
+//   - POST /sockets/{id}/send    发送消息给连接，包体 {"path":"","data":{}}
+//   - POST /users/{uuid}/send    发送消息给用户的所有连接
+//   - POST /broadcast            广播，包体 {"path":"","data":{},"authenticated":false}
+//   - GET  /routes               已注册的路由
+//   - GET  /listeners            网络监听器和事件监听函数
+func New(ss *cosnet.Sockets) *Handler {
+	h := &Handler{sockets: ss, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /sockets", h.list)
+	h.mux.HandleFunc("GET /sockets/{id}", h.detail)
+	h.mux.HandleFunc("POST /sockets/{id}/kick", h.kick)
+	h.mux.HandleFunc("POST /sockets/{id}/send", h.sendSocket)
+	h.mux.HandleFunc("POST /users/{uuid}/send", h.sendUser)
+	h.mux.HandleFunc("POST /broadcast", h.broadcast)
+	h.mux.HandleFunc("GET /routes", h.routes)
+	h.mux.HandleFunc("GET /listeners", h.listeners)
+	return h
+}
+
+// Handler 连接管理 HTTP 接口。
+type Handler struct {
+	mux     *http.ServeMux
+	sockets *cosnet.Sockets
+	// Auth 权限检查，返回 false 时拒绝请求(403)，为 nil 时不检查
+	Auth func(r *http.Request) bool
+	// KickPath 踢下线时通知客户端的协议，包体为 reason，为空时不通知直接关闭
+	KickPath string
+	// KickDelay 踢下线后延时关闭的时间，单位秒，用于发送通知
+	KickDelay int32
+}
+
+// ServeHTTP 实现 http.Handler 接口。
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Auth != nil && !h.Auth(r) {
+		h.error(w, http.StatusForbidden, errors.New("forbidden"))
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// SocketInfo 连接信息。
+type SocketInfo struct {
+	Id         uint64        `json:"id"`
+	Type       string        `json:"type"`
+	Status     string        `json:"status"`
+	User       string        `json:"user,omitempty"`
+	LocalAddr  string        `json:"local_addr,omitempty"`
+	RemoteAddr string        `json:"remote_addr,omitempty"`
+	Created    time.Time     `json:"created"`
+	Age        int64         `json:"age"`
+	Pending    int           `json:"pending"`
+	Attributes values.Values `json:"attributes,omitempty"`
+}
+
+// sendArgs 发送消息的参数。
+type sendArgs struct {
+	Path          string          `json:"path"`
+	Data          json.RawMessage `json:"data"`
+	Authenticated bool            `json:"authenticated"` // 仅广播使用，只发送给已认证的连接
+}
+
+// statusName Socket 状态名称。
+var statusName = map[int32]string{
+	cosnet.SocketStatusNone:         "none",
+	cosnet.SocketStatusConnected:    "connected",
+	cosnet.SocketStatusClosing:      "closing",
+	cosnet.SocketStatusDisconnect:   "disconnect",
+	cosnet.SocketStatusDisconnected: "disconnected",
+	cosnet.SocketStatusReconnecting: "reconnecting",
+	cosnet.SocketStatusReleased:     "released",
+}
+
+func newSocketInfo(sock *cosnet.Socket, detail bool) *SocketInfo {
+	info := &SocketInfo{
+		Id:      sock.Id(),
+		Type:    "server",
+		Status:  statusName[sock.Status()],
+		User:    sock.Data().UUID(),
+		Created: sock.Created(),
+		Age:     int64(time.Since(sock.Created()).Seconds()),
+		Pending: sock.Pending(),
+	}
+	if sock.Type() == listener.SocketTypeClient {
+		info.Type = "client"
+	}
+	if addr := sock.LocalAddr(); addr != nil {
+		info.LocalAddr = addr.String()
+	}
+	if addr := sock.RemoteAddr(); addr != nil {
+		info.RemoteAddr = addr.String()
+	}
+	if detail {
+		info.Attributes = sock.Attributes()
+	}
+	return info
+}
+
+// list 连接列表。
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	user := query.Get("user")
+	state := query.Get("state")
+	var ip func(addr string) bool
+	if v := query.Get("ip"); v != "" {
+		var err error
+		if ip, err = ipMatcher(v); err != nil {
+			h.error(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	age, _ := strconv.ParseInt(query.Get("age"), 10, 64)
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	var rows []*SocketInfo
+	h.sockets.Range(func(sock *cosnet.Socket) bool {
+		info := newSocketInfo(sock, false)
+		if user != "" && info.User != user {
+			return true
+		}
+		if state != "" && info.Status != state {
+			return true
+		}
+		if age > 0 && info.Age < age {
+			return true
+		}
+		if ip != nil && !ip(info.RemoteAddr) {
+			return true
+		}
+		rows = append(rows, info)
+		return true
+	})
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Id < rows[j].Id
+	})
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	h.json(w, map[string]any{"total": h.sockets.Count(), "rows": rows})
+}
+
+// detail 单个连接详情。
+func (h *Handler) detail(w http.ResponseWriter, r *http.Request) {
+	sock, err := h.socket(r)
+	if err != nil {
+		h.error(w, http.StatusNotFound, err)
+		return
+	}
+	h.json(w, newSocketInfo(sock, true))
+}
+
+// kick 踢下线。
+func (h *Handler) kick(w http.ResponseWriter, r *http.Request) {
+	sock, err := h.socket(r)
+	if err != nil {
+		h.error(w, http.StatusNotFound, err)
+		return
+	}
+	reason := r.FormValue("reason")
+	if h.KickPath != "" {
+		_ = sock.Send(message.FlagNoreply, 0, h.KickPath, reason)
+	}
+	sock.Close(h.KickDelay)
+	h.json(w, map[string]any{"id": sock.Id(), "reason": reason})
+}
+
+// sendSocket 发送消息给连接。
+func (h *Handler) sendSocket(w http.ResponseWriter, r *http.Request) {
+	sock, err := h.socket(r)
+	if err != nil {
+		h.error(w, http.StatusNotFound, err)
+		return
+	}
+	args, err := h.args(r)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = sock.Send(message.FlagNoreply, 0, args.Path, []byte(args.Data)); err != nil {
+		h.error(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.json(w, map[string]any{"sent": 1})
+}
+
+// sendUser 发送消息给用户的所有连接。
+func (h *Handler) sendUser(w http.ResponseWriter, r *http.Request) {
+	uuid := r.PathValue("uuid")
+	args, err := h.args(r)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err)
+		return
+	}
+	var sent int
+	h.sockets.Range(func(sock *cosnet.Socket) bool {
+		if sock.Data().UUID() == uuid && sock.Send(message.FlagNoreply, 0, args.Path, []byte(args.Data)) == nil {
+			sent++
+		}
+		return true
+	})
+	if sent == 0 {
+		h.error(w, http.StatusNotFound, errors.New("user not online"))
+		return
+	}
+	h.json(w, map[string]any{"sent": sent})
+}
+
+// broadcast 广播。
+func (h *Handler) broadcast(w http.ResponseWriter, r *http.Request) {
+	args, err := h.args(r)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err)
+		return
+	}
+	codec := h.sockets.Options.Codec
+	m := message.Require(codec)
+	defer message.Release(m)
+	if err = m.Marshal(codec.DefaultMagic(), message.FlagBroadcast|message.FlagNoreply, 0, args.Path, []byte(args.Data)); err != nil {
+		h.error(w, http.StatusBadRequest, err)
+		return
+	}
+	var filter func(*cosnet.Socket) bool
+	if args.Authenticated {
+		filter = func(sock *cosnet.Socket) bool {
+			return sock.Data() != nil
+		}
+	}
+	h.sockets.Broadcast(m, filter)
+	h.json(w, map[string]any{"path": args.Path})
+}
+
+// routes 已注册的路由。
+func (h *Handler) routes(w http.ResponseWriter, _ *http.Request) {
+	var rows []string
+	h.sockets.Registry.Nodes(func(node *registry.Node) bool {
+		rows = append(rows, node.Name())
+		return true
+	})
+	sort.Strings(rows)
+	h.json(w, rows)
+}
+
+// listeners 网络监听器和事件监听函数数量。
+func (h *Handler) listeners(w http.ResponseWriter, _ *http.Request) {
+	var addrs []string
+	for _, ln := range h.sockets.Listeners() {
+		if addr := ln.Addr(); addr != nil {
+			addrs = append(addrs, addr.Network()+"://"+addr.String())
+		}
+	}
+	events := map[string]int{}
+	for e, name := range eventName {
+		if n := h.sockets.Subscribers(e); n > 0 {
+			events[name] = n
+		}
+	}
+	h.json(w, map[string]any{"listeners": addrs, "events": events})
+}
+
+// eventName 事件名称。
+var eventName = map[cosnet.EventType]string{
+	cosnet.EventTypeError:            "error",
+	cosnet.EventTypeHeartbeat:        "heartbeat",
+	cosnet.EventTypeMessage:          "message",
+	cosnet.EventTypeConnected:        "connected",
+	cosnet.EventTypeReconnected:      "reconnected",
+	cosnet.EventTypeDisconnect:       "disconnect",
+	cosnet.EventTypeAuthentication:   "authentication",
+	cosnet.EventTypeReplaced:         "replaced",
+	cosnet.EventTypeUnauthorized:     "unauthorized",
+	cosnet.EventTypeOverload:         "overload",
+	cosnet.EventTypeReleased:         "released",
+	cosnet.EventTypeReconnectFailed:  "reconnect_failed",
+	cosnet.EventTypeMessageDropped:   "message_dropped",
+	cosnet.EventTypeHeartbeatTimeout: "heartbeat_timeout",
+	cosnet.EventTypeReconnecting:     "reconnecting",
+	cosnet.EventTypeRejected:         "rejected",
+}
+
+func (h *Handler) socket(r *http.Request) (*cosnet.Socket, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	sock := h.sockets.Get(id)
+	if sock == nil {
+		return nil, errors.New("socket not found")
+	}
+	return sock, nil
+}
+
+func (h *Handler) args(r *http.Request) (*sendArgs, error) {
+	args := &sendArgs{}
+	if err := json.NewDecoder(r.Body).Decode(args); err != nil {
+		return nil, err
+	}
+	if args.Path == "" {
+		return nil, errors.New("path empty")
+	}
+	return args, nil
+}
+
+func (h *Handler) json(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) error(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+}
+
+// ipMatcher 解析 ip 过滤参数，包含 "/" 时按 CIDR 匹配网段，否则匹配单个 IP。
+func ipMatcher(v string) (func(addr string) bool, error) {
+	if strings.Contains(v, "/") {
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		return func(addr string) bool {
+			ip := net.ParseIP(host(addr))
+			return ip != nil && network.Contains(ip)
+		}, nil
+	}
+	target := net.ParseIP(v)
+	if target == nil {
+		return nil, errors.New("invalid ip: " + v)
+	}
+	return func(addr string) bool {
+		return target.Equal(net.ParseIP(host(addr)))
+	}, nil
+}
+
+// host 去掉地址中的端口。
+func host(addr string) string {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		return h
+	}
+	return addr
+}
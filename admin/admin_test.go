@@ -0,0 +1,330 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosgo/session"
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/cosnet/tcp"
+)
+
+type testHandler struct{}
+
+func (testHandler) Echo(c *cosnet.Context) any {
+	return nil
+}
+
+func TestAdminAuth(t *testing.T) {
+	h := New(cosnet.New())
+	h.Auth = func(r *http.Request) bool {
+		return r.Header.Get("X-Token") == "secret"
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sockets", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("without token: got %d, want 403", w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/sockets", nil)
+	r.Header.Set("X-Token", "secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("with token: got %d, want 200", w.Code)
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	ss := cosnet.New()
+	if err := ss.Register(&testHandler{}); err != nil {
+		t.Fatal(err)
+	}
+	h := New(ss)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/routes", nil))
+	var routes []string
+	if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil {
+		t.Fatalf("decode routes: %v", err)
+	}
+	if len(routes) != 1 || routes[0] != "/testhandler/echo" {
+		t.Errorf("routes: got %v, want [/testhandler/echo]", routes)
+	}
+}
+
+func TestAdminSocketNotFound(t *testing.T) {
+	h := New(cosnet.New())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sockets/1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("detail: got %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sockets/1/kick", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("kick: got %d, want 404", w.Code)
+	}
+}
+
+// testMessage 客户端收到的消息
+type testMessage struct {
+	path string
+	body string
+}
+
+// testServer 创建监听随机端口的服务器，/login 使用包体作为 UUID 完成身份认证
+func testServer(t *testing.T) (*cosnet.Sockets, string) {
+	t.Helper()
+	ss := cosnet.New()
+	if err := ss.Service().Register(func(c *cosnet.Context) any {
+		c.Socket.Authentication(session.NewData(string(c.Message.Body()), nil))
+		return []byte("ok")
+	}, "/login"); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tcp.New("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.Accept(ln)
+	t.Cleanup(func() {
+		_ = ln.Close()
+		ss.Range(func(sock *cosnet.Socket) bool {
+			sock.Close()
+			return true
+		})
+	})
+	return ss, "tcp://" + ln.Addr().String()
+}
+
+// testClient 连接服务器，uuid 不为空时登录，返回服务器端的 Socket 和客户端收到的消息
+func testClient(t *testing.T, ss *cosnet.Sockets, address, uuid string) (*cosnet.Socket, <-chan testMessage) {
+	t.Helper()
+	cl := cosnet.New()
+	received := make(chan testMessage, 10)
+	cl.On(cosnet.EventTypeMessage, func(_ *cosnet.Socket, v any) {
+		m := v.(message.Message)
+		path, _, _ := m.Path()
+		received <- testMessage{path: path, body: string(m.Body())}
+	})
+	sock, err := cl.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	if uuid != "" {
+		if err = sock.Send(0, 1, "/login", []byte(uuid)); err != nil {
+			t.Fatal(err)
+		}
+		testReceive(t, received)
+	}
+	// 通过客户端的本地地址找到服务器端的 Socket
+	local := sock.LocalAddr().String()
+	var server *cosnet.Socket
+	deadline := time.Now().Add(2 * time.Second)
+	for server == nil {
+		ss.Range(func(s *cosnet.Socket) bool {
+			if addr := s.RemoteAddr(); addr != nil && addr.String() == local {
+				server = s
+				return false
+			}
+			return true
+		})
+		if server == nil && time.Now().After(deadline) {
+			t.Fatal("server socket not found")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return server, received
+}
+
+// testReceive 等待客户端收到下一条消息
+func testReceive(t *testing.T, received <-chan testMessage) testMessage {
+	t.Helper()
+	select {
+	case m := <-received:
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatal("receive timeout")
+	}
+	return testMessage{}
+}
+
+// testRequest 发送请求并解析 JSON 响应
+func testRequest(t *testing.T, h http.Handler, method, target, body string, v any) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if method == http.MethodPost && !strings.HasPrefix(body, "{") {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	h.ServeHTTP(w, r)
+	if v != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%v %v decode: %v", method, target, err)
+		}
+	}
+	return w.Code
+}
+
+func TestAdminList(t *testing.T) {
+	ss, address := testServer(t)
+	alice, _ := testClient(t, ss, address, "alice")
+	guest, _ := testClient(t, ss, address, "")
+	h := New(ss)
+
+	ids := func(query string) []uint64 {
+		t.Helper()
+		var res struct {
+			Total int64         `json:"total"`
+			Rows  []*SocketInfo `json:"rows"`
+		}
+		if code := testRequest(t, h, http.MethodGet, "/sockets"+query, "", &res); code != http.StatusOK {
+			t.Fatalf("list %v: got %d", query, code)
+		}
+		var r []uint64
+		for _, row := range res.Rows {
+			r = append(r, row.Id)
+		}
+		return r
+	}
+	all := fmt.Sprint([]uint64{alice.Id(), guest.Id()})
+	for query, want := range map[string]string{
+		"":                      all,
+		"?user=alice":           fmt.Sprint([]uint64{alice.Id()}),
+		"?user=bob":             "[]",
+		"?state=connected":      all,
+		"?state=closing":        "[]",
+		"?age=3600":             "[]",
+		"?ip=127.0.0.1":         all,
+		"?ip=127.0.0.10":        "[]",
+		"?ip=127.0.0.0/8":       all,
+		"?ip=10.0.0.0/8":        "[]",
+		"?ip=127.0.0.1&limit=1": fmt.Sprint([]uint64{alice.Id()}),
+	} {
+		if got := fmt.Sprint(ids(query)); got != want {
+			t.Errorf("list %q: got %v, want %v", query, got, want)
+		}
+	}
+	for _, ip := range []string{"127.0.0", "10.0.0.0/33"} {
+		if code := testRequest(t, h, http.MethodGet, "/sockets?ip="+ip, "", nil); code != http.StatusBadRequest {
+			t.Errorf("ip %q: got %d, want 400", ip, code)
+		}
+	}
+}
+
+func TestIPMatcher(t *testing.T) {
+	for _, tc := range []struct {
+		filter, addr string
+		match        bool
+	}{
+		{"10.0.0.1", "10.0.0.1:8080", true},
+		{"10.0.0.1", "10.0.0.10:8080", false},
+		{"10.0.0.0/24", "10.0.0.10:8080", true},
+		{"10.0.0.0/24", "10.0.1.1:8080", false},
+		{"::1", "[::1]:8080", true},
+		{"10.0.0.1", "@game", false},
+	} {
+		m, err := ipMatcher(tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m(tc.addr) != tc.match {
+			t.Errorf("ip %v addr %v: got %v", tc.filter, tc.addr, !tc.match)
+		}
+	}
+}
+
+func TestAdminDetail(t *testing.T) {
+	ss, address := testServer(t)
+	sock, _ := testClient(t, ss, address, "alice")
+	sock.Set("level", 3)
+	h := New(ss)
+
+	var info SocketInfo
+	if code := testRequest(t, h, http.MethodGet, fmt.Sprintf("/sockets/%d", sock.Id()), "", &info); code != http.StatusOK {
+		t.Fatalf("detail: got %d", code)
+	}
+	if info.Id != sock.Id() || info.User != "alice" || info.Status != "connected" || info.Type != "server" {
+		t.Errorf("detail: %+v", info)
+	}
+	if info.RemoteAddr == "" || info.LocalAddr == "" || info.Created.IsZero() || info.Attributes.GetInt32("level") != 3 {
+		t.Errorf("detail stats: %+v", info)
+	}
+}
+
+func TestAdminKick(t *testing.T) {
+	ss, address := testServer(t)
+	sock, received := testClient(t, ss, address, "")
+	h := New(ss)
+	h.KickPath = "/kick"
+	h.KickDelay = 1
+
+	var res map[string]any
+	if code := testRequest(t, h, http.MethodPost, fmt.Sprintf("/sockets/%d/kick", sock.Id()), "reason=maintenance", &res); code != http.StatusOK {
+		t.Fatalf("kick: got %d", code)
+	}
+	if res["reason"] != "maintenance" {
+		t.Errorf("kick response: %v", res)
+	}
+	if m := testReceive(t, received); m.path != "/kick" || !strings.Contains(m.body, "maintenance") {
+		t.Errorf("kick notice: %+v", m)
+	}
+	if sock.Status() != cosnet.SocketStatusClosing {
+		t.Errorf("kicked socket status %d", sock.Status())
+	}
+}
+
+func TestAdminSend(t *testing.T) {
+	ss, address := testServer(t)
+	alice1, received1 := testClient(t, ss, address, "alice")
+	_, received2 := testClient(t, ss, address, "alice")
+	_, received3 := testClient(t, ss, address, "")
+	h := New(ss)
+
+	var res map[string]any
+	if code := testRequest(t, h, http.MethodPost, fmt.Sprintf("/sockets/%d/send", alice1.Id()), `{"path":"/notify","data":{"n":1}}`, &res); code != http.StatusOK {
+		t.Fatalf("send socket: got %d", code)
+	}
+	if m := testReceive(t, received1); m.path != "/notify" || m.body != `{"n":1}` {
+		t.Errorf("send socket: %+v", m)
+	}
+
+	if code := testRequest(t, h, http.MethodPost, "/users/alice/send", `{"path":"/user","data":2}`, &res); code != http.StatusOK || res["sent"] != float64(2) {
+		t.Fatalf("send user: got %d %v", code, res)
+	}
+	for _, received := range []<-chan testMessage{received1, received2} {
+		if m := testReceive(t, received); m.path != "/user" || m.body != "2" {
+			t.Errorf("send user: %+v", m)
+		}
+	}
+	if code := testRequest(t, h, http.MethodPost, "/users/bob/send", `{"path":"/user","data":2}`, nil); code != http.StatusNotFound {
+		t.Errorf("send offline user: got %d, want 404", code)
+	}
+
+	// 只广播给已认证的连接
+	if code := testRequest(t, h, http.MethodPost, "/broadcast", `{"path":"/news","data":"hi","authenticated":true}`, &res); code != http.StatusOK {
+		t.Fatalf("broadcast: got %d", code)
+	}
+	for _, received := range []<-chan testMessage{received1, received2} {
+		if m := testReceive(t, received); m.path != "/news" || m.body != `"hi"` {
+			t.Errorf("broadcast: %+v", m)
+		}
+	}
+	if code := testRequest(t, h, http.MethodPost, "/broadcast", `{"path":"/all","data":"hi"}`, &res); code != http.StatusOK {
+		t.Fatalf("broadcast: got %d", code)
+	}
+	if m := testReceive(t, received3); m.path != "/all" {
+		t.Errorf("broadcast to unauthenticated socket: %+v", m)
+	}
+}
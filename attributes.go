@@ -0,0 +1,113 @@
+package cosnet
+
+import (
+	"sync"
+
+	"github.com/hwcer/cosgo/values"
+)
+
+// attributes Socket 的属性存储，并发安全。
+// 与 Socket.Data 不同，属性在连接建立时即可使用，客户端模式断线重连后依然保留，Socket 销毁时清空。
+type attributes struct {
+	mutex sync.RWMutex
+	dict  values.Values
+}
+
+func (attr *attributes) get(key string) (any, bool) {
+	attr.mutex.RLock()
+	defer attr.mutex.RUnlock()
+	v, ok := attr.dict[key]
+	return v, ok
+}
+
+func (attr *attributes) set(key string, value any) {
+	attr.mutex.Lock()
+	defer attr.mutex.Unlock()
+	if attr.dict == nil {
+		attr.dict = values.Values{}
+	}
+	attr.dict[key] = value
+}
+
+func (attr *attributes) delete(key string) {
+	attr.mutex.Lock()
+	defer attr.mutex.Unlock()
+	delete(attr.dict, key)
+}
+
+func (attr *attributes) reset() {
+	attr.mutex.Lock()
+	defer attr.mutex.Unlock()
+	attr.dict = nil
+}
+
+func (attr *attributes) clone() values.Values {
+	attr.mutex.RLock()
+	defer attr.mutex.RUnlock()
+	return attr.dict.Clone()
+}
+
+// Set 设置属性，例如握手随机数、客户端版本、设备号等认证前就需要保存的数据。
+// 参数:
+//   - key: 属性名
+//   - value: 属性值
+func (sock *Socket) Set(key string, value any) {
+	sock.attributes.set(key, value)
+}
+
+// Get 获取属性。
+// 参数 key: 属性名。
+// 返回值: 属性值，不存在时返回 nil。
+func (sock *Socket) Get(key string) any {
+	v, _ := sock.attributes.get(key)
+	return v
+}
+
+// Has 判断属性是否存在。
+// 参数 key: 属性名。
+func (sock *Socket) Has(key string) bool {
+	_, ok := sock.attributes.get(key)
+	return ok
+}
+
+// Delete 删除属性。
+// 参数 key: 属性名。
+func (sock *Socket) Delete(key string) {
+	sock.attributes.delete(key)
+}
+
+// Attributes 获取所有属性的副本。
+func (sock *Socket) Attributes() values.Values {
+	return sock.attributes.clone()
+}
+
+// GetString 获取字符串类型的属性。
+func (sock *Socket) GetString(key string) string {
+	return values.ParseString(sock.Get(key))
+}
+
+// GetInt 获取 int 类型的属性。
+func (sock *Socket) GetInt(key string) int {
+	return int(values.ParseInt64(sock.Get(key)))
+}
+
+// GetInt32 获取 int32 类型的属性。
+func (sock *Socket) GetInt32(key string) int32 {
+	return values.ParseInt32(sock.Get(key))
+}
+
+// GetInt64 获取 int64 类型的属性。
+func (sock *Socket) GetInt64(key string) int64 {
+	return values.ParseInt64(sock.Get(key))
+}
+
+// GetFloat64 获取 float64 类型的属性。
+func (sock *Socket) GetFloat64(key string) float64 {
+	return values.ParseFloat64(sock.Get(key))
+}
+
+// GetBool 获取 bool 类型的属性，非 bool 类型返回 false。
+func (sock *Socket) GetBool(key string) bool {
+	v, _ := sock.Get(key).(bool)
+	return v
+}
@@ -0,0 +1,76 @@
+package cosnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttributes(t *testing.T) {
+	released := make(chan *Socket, 1)
+	_, address := testServer(t, func(ss *Sockets) {
+		ss.On(EventTypeReleased, func(s *Socket, _ any) {
+			released <- s
+		})
+		service := ss.Service()
+		_ = service.Register(func(c *Context) any {
+			c.Socket.Set("version", "1.2.0")
+			c.Socket.Set("level", 3)
+			return nil
+		}, "/hello")
+		_ = service.Register(func(c *Context) any {
+			if !c.Socket.Has("level") || c.Socket.GetInt32("level") != 3 {
+				return NewError(ErrorCodeBadRequest, "level")
+			}
+			c.Socket.Delete("level")
+			return []byte(c.Socket.GetString("version"))
+		}, "/version")
+	})
+	sock, replies := testClient(t, address)
+	_ = sock.Send(0, 1, "/hello", nil)
+	testReceive(t, replies)
+	_ = sock.Send(0, 2, "/version", nil)
+	if r := testReceive(t, replies); string(r.body) != "1.2.0" {
+		t.Fatalf("attribute reply %+v", r)
+	}
+
+	sock.Close()
+	sock.sockets.Heartbeat(1)
+	select {
+	case s := <-released:
+		// 属性在 EventTypeReleased 事件之后清空
+		testEventually(t, testTimeout, func() bool {
+			return !s.Has("version") && len(s.Attributes()) == 0
+		})
+	case <-time.After(testTimeout):
+		t.Fatal("server socket not released")
+	}
+}
+
+// 客户端断线重连后属性保留
+func TestAttributesReconnect(t *testing.T) {
+	ss, address := testServer(t)
+	token := make(chan string, 1)
+	opts := &DialOptions{
+		AfterReconnect: func(sock *Socket) error {
+			token <- sock.GetString("token")
+			return nil
+		},
+	}
+	sock, _ := testClientWithOptions(t, address, opts)
+	sock.Set("token", "abc")
+
+	// 服务器断开连接，客户端自动重连
+	testServerSocket(t, ss).Close()
+	ss.Heartbeat(1)
+	select {
+	case v := <-token:
+		if v != "abc" {
+			t.Fatalf("attribute after reconnect %q", v)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("client not reconnected")
+	}
+	if v := sock.GetString("token"); v != "abc" {
+		t.Fatalf("attribute after AfterReconnect %q", v)
+	}
+}
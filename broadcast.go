@@ -0,0 +1,61 @@
+package cosnet
+
+import (
+	"github.com/hwcer/cosnet/message"
+)
+
+// Broadcast 广播消息。
+// 包体只编码一次，每个 Socket 复制一份后以非阻塞方式写入发送通道，写通道已满的 Socket 丢弃该消息。
+// 设置了 Bus 且 filter 为 nil 时同时发送到其它节点；filter 无法跨节点传递，不为 nil 时只发送给本节点，跨节点分组广播使用 BroadcastGroup。
+// m 的所有权仍属于调用方，调用方负责回收。
+// 参数:
+//   - m: 要广播的消息
+//   - filter: 过滤函数，返回 false 的 Socket 不发送，为 nil 时发送给所有 Socket
+func (ss *Sockets) Broadcast(m message.Message, filter func(*Socket) bool) {
+	if filter == nil {
+		ss.publishBroadcast(m, "")
+	}
+	ss.broadcast(m, filter)
+}
+
+// BroadcastGroup 广播消息给加入了分组的 Socket，设置了 Bus 时同时发送到其它节点。
+// m 的所有权仍属于调用方，调用方负责回收。
+// 参数:
+//   - group: 分组名称，参考 Socket.Join
+//   - m: 要广播的消息
+func (ss *Sockets) BroadcastGroup(group string, m message.Message) {
+	ss.publishBroadcast(m, group)
+	ss.broadcast(m, func(sock *Socket) bool { return sock.InGroup(group) })
+}
+
+// broadcast 广播消息给本节点的 Socket
+func (ss *Sockets) broadcast(m message.Message, filter func(*Socket) bool) {
+	magic := m.Magic()
+	if magic == nil {
+		return
+	}
+	var path any
+	if magic.Type == message.MagicTypePath {
+		p, q, err := m.Path()
+		if err != nil {
+			ss.Errorf(nil, "broadcast message path error:%v", err)
+			return
+		}
+		if q != "" {
+			p = p + "?" + q
+		}
+		path = p
+	} else {
+		path = m.Code()
+	}
+	flag := m.Flag()
+	index := m.Index()
+	body := m.Body()
+	ss.Range(func(sock *Socket) bool {
+		if !sock.IsReady() || (filter != nil && !filter(sock)) {
+			return true
+		}
+		_ = sock.SendWithMagic(magic.Key, flag, index, path, body, false)
+		return true
+	})
+}
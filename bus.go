@@ -0,0 +1,157 @@
+package cosnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hwcer/cosnet/message"
+)
+
+// BroadcastChannel 跨节点广播使用的总线频道。
+const BroadcastChannel = "cosnet.broadcast"
+
+// broadcastRecentSize 每个节点记录的最近广播数量，用于丢弃重复投递的广播
+const broadcastRecentSize = 4096
+
+// broadcastHeadSize 广播帧头长度：来源(8) + 序号(8) + 分组长度(2)
+const broadcastHeadSize = 18
+
+// Bus 跨节点广播总线，按频道发布和订阅消息，参考 bus 包中的实现。
+type Bus interface {
+	// Publish 发布消息到频道，订阅该频道的所有节点（可能包括自己）都会收到。
+	Publish(channel string, data []byte) error
+	// Subscribe 订阅频道，返回取消订阅的函数，handler 返回后 data 可能被复用，需要保留时应复制。
+	Subscribe(channel string, handler func(data []byte)) (cancel func(), err error)
+}
+
+// busBinding Sockets 与总线的绑定
+type busBinding struct {
+	bus    Bus
+	cancel func()
+	origin uint64 // 本实例的随机标识，用于识别自己发布的广播
+	seq    atomic.Uint64
+	recent broadcastRecent
+}
+
+// broadcastKey 广播的唯一标识
+type broadcastKey struct {
+	origin uint64
+	seq    uint64
+}
+
+// broadcastRecent 最近收到的广播，固定容量，超出后淘汰最早的记录
+type broadcastRecent struct {
+	mutex sync.Mutex
+	dict  map[broadcastKey]struct{}
+	ring  []broadcastKey
+	next  int
+}
+
+// seen 记录广播，已经记录过时返回 true
+func (r *broadcastRecent) seen(k broadcastKey) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.dict[k]; ok {
+		return true
+	}
+	if r.dict == nil {
+		r.dict = make(map[broadcastKey]struct{}, broadcastRecentSize)
+		r.ring = make([]broadcastKey, broadcastRecentSize)
+	}
+	if old := r.ring[r.next]; old.origin != 0 {
+		delete(r.dict, old)
+	}
+	r.ring[r.next] = k
+	r.next = (r.next + 1) % broadcastRecentSize
+	r.dict[k] = struct{}{}
+	return false
+}
+
+// SetBus 设置跨节点广播总线，设置后 Broadcast（filter 为 nil）和 BroadcastGroup 会同时发送到其它节点。
+// 同一条广播在每个节点只投递一次，重复收到的广播会被丢弃。
+// 参数 bus: 广播总线，为 nil 时取消跨节点广播。
+// 返回值: 订阅失败时返回错误。
+func (ss *Sockets) SetBus(bus Bus) error {
+	var b *busBinding
+	if bus != nil {
+		b = &busBinding{bus: bus, origin: rand.Uint64() | 1}
+		cancel, err := bus.Subscribe(BroadcastChannel, func(data []byte) {
+			ss.receiveBroadcast(b, data)
+		})
+		if err != nil {
+			return err
+		}
+		b.cancel = cancel
+	}
+	if old := ss.bus.Swap(b); old != nil && old.cancel != nil {
+		old.cancel()
+	}
+	return nil
+}
+
+// Bus 当前使用的跨节点广播总线，未设置时返回 nil。
+func (ss *Sockets) Bus() Bus {
+	if b := ss.bus.Load(); b != nil {
+		return b.bus
+	}
+	return nil
+}
+
+// publishBroadcast 将广播编码一次后发布到总线
+func (ss *Sockets) publishBroadcast(m message.Message, group string) {
+	b := ss.bus.Load()
+	if b == nil {
+		return
+	}
+	if len(group) > 0xFFFF {
+		ss.Errorf(nil, "broadcast group too long:%d", len(group))
+		return
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, broadcastHeadSize+len(group)+int(m.Size())+16))
+	var head [broadcastHeadSize]byte
+	binary.BigEndian.PutUint64(head[0:8], b.origin)
+	binary.BigEndian.PutUint64(head[8:16], b.seq.Add(1))
+	binary.BigEndian.PutUint16(head[16:18], uint16(len(group)))
+	buf.Write(head[:])
+	buf.WriteString(group)
+	if _, err := m.Bytes(buf, true); err != nil {
+		ss.Errorf(nil, "broadcast encode error:%v", err)
+		return
+	}
+	if err := b.bus.Publish(BroadcastChannel, buf.Bytes()); err != nil {
+		ss.Errorf(nil, "broadcast publish error:%v", err)
+	}
+}
+
+// receiveBroadcast 处理总线上的广播，只投递给本节点的连接
+func (ss *Sockets) receiveBroadcast(b *busBinding, data []byte) {
+	if len(data) < broadcastHeadSize {
+		ss.Errorf(nil, "broadcast frame error:%v", errors.New("frame too short"))
+		return
+	}
+	k := broadcastKey{origin: binary.BigEndian.Uint64(data[0:8]), seq: binary.BigEndian.Uint64(data[8:16])}
+	if k.origin == b.origin || b.recent.seen(k) {
+		return
+	}
+	n := broadcastHeadSize + int(binary.BigEndian.Uint16(data[16:18]))
+	if len(data) < n {
+		ss.Errorf(nil, "broadcast frame error:%v", errors.New("group too long"))
+		return
+	}
+	group := string(data[broadcastHeadSize:n])
+	m := message.Require(ss.Options.Codec)
+	defer message.Release(m)
+	if err := m.Reset(bytes.Clone(data[n:])); err != nil {
+		ss.Errorf(nil, "broadcast frame error:%v", err)
+		return
+	}
+	if group == "" {
+		ss.broadcast(m, nil)
+	} else {
+		ss.broadcast(m, func(sock *Socket) bool { return sock.InGroup(group) })
+	}
+}
@@ -0,0 +1,141 @@
+package bus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/message"
+)
+
+var (
+	_ cosnet.Bus = (*Memory)(nil)
+	_ cosnet.Bus = (*Client)(nil)
+)
+
+// startNode 启动一个节点并连接一个客户端，返回节点和客户端收到的消息
+func startNode(t *testing.T, bus cosnet.Bus, address string) (*cosnet.Sockets, chan string) {
+	ss := cosnet.New()
+	if err := ss.SetBus(bus); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ss.Listen(address); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.Start(); err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan string, 8)
+	cl := cosnet.New()
+	cl.On(cosnet.EventTypeMessage, func(_ *cosnet.Socket, v any) {
+		m := v.(message.Message)
+		path, _, _ := m.Path()
+		got <- path + ":" + string(m.Body())
+	})
+	sock, err := cl.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	deadline := time.Now().Add(2 * time.Second)
+	for ss.Count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return ss, got
+}
+
+func broadcast(t *testing.T, path string, body string) message.Message {
+	m := message.Require()
+	if err := m.Marshal((*message.Codec)(nil).DefaultMagic(), message.FlagBroadcast|message.FlagNoreply, 0, path, []byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func expect(t *testing.T, got chan string, want string) {
+	t.Helper()
+	select {
+	case v := <-got:
+		if v != want {
+			t.Fatalf("got %q, want %q", v, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting %q", want)
+	}
+}
+
+func expectNone(t *testing.T, got chan string) {
+	t.Helper()
+	select {
+	case v := <-got:
+		t.Fatalf("unexpected message %q", v)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func testBroadcast(t *testing.T, buses [2]cosnet.Bus, port int) {
+	ss1, got1 := startNode(t, buses[0], fmt.Sprintf("tcp://127.0.0.1:%d", port))
+	ss2, got2 := startNode(t, buses[1], fmt.Sprintf("tcp://127.0.0.1:%d", port+1))
+
+	m := broadcast(t, "/all", "hi")
+	ss1.Broadcast(m, nil)
+	message.Release(m)
+	expect(t, got1, "/all:hi")
+	expect(t, got2, "/all:hi")
+
+	ss2.Range(func(sock *cosnet.Socket) bool {
+		sock.Join("vip")
+		return true
+	})
+	m = broadcast(t, "/vip", "v")
+	ss1.BroadcastGroup("vip", m)
+	message.Release(m)
+	expect(t, got2, "/vip:v")
+	expectNone(t, got1)
+	expectNone(t, got2)
+}
+
+func TestMemory(t *testing.T) {
+	b := NewMemory()
+	testBroadcast(t, [2]cosnet.Bus{b, b}, 19201)
+}
+
+func TestTCP(t *testing.T) {
+	s := NewServer()
+	if err := s.Listen("tcp://127.0.0.1:19210"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	var buses [2]cosnet.Bus
+	for i := range buses {
+		c, err := Dial("tcp://127.0.0.1:19210")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(c.Close)
+		buses[i] = c
+	}
+	// 等待服务器处理订阅
+	time.Sleep(100 * time.Millisecond)
+	testBroadcast(t, buses, 19211)
+}
+
+func TestDedupe(t *testing.T) {
+	// 同一节点通过两条总线收到同一条广播时只投递一次
+	b := NewMemory()
+	_, got := startNode(t, b, "tcp://127.0.0.1:19221")
+	other := cosnet.New()
+	if err := other.SetBus(b); err != nil {
+		t.Fatal(err)
+	}
+	var frame []byte
+	cancel, _ := b.Subscribe(cosnet.BroadcastChannel, func(data []byte) { frame = append([]byte(nil), data...) })
+	m := broadcast(t, "/once", "1")
+	other.Broadcast(m, nil)
+	message.Release(m)
+	cancel()
+	expect(t, got, "/once:1")
+	_ = b.Publish(cosnet.BroadcastChannel, frame)
+	expectNone(t, got)
+}
@@ -0,0 +1,157 @@
+package bus
+
+import (
+	"sync"
+
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// Dial 连接 TCP 总线服务器，断线后自动重连并恢复订阅。
+// 客户端使用独立的 Sockets 实例，可以通过 Client.Sockets 修改重连等配置。
+// 参数:
+//   - address: 总线服务器地址，格式与 Sockets.Connect 相同
+//   - opts: 连接选项，可选，AfterReconnect 会在恢复订阅之后调用
+//
+// 返回值: 总线客户端，连接失败时返回错误
+func Dial(address string, opts ...*cosnet.DialOptions) (*Client, error) {
+	c := &Client{Sockets: cosnet.New(), subs: map[string]map[uint64]func([]byte){}}
+	if err := c.Sockets.Service("").Register(c.receive, MessagePath); err != nil {
+		return nil, err
+	}
+	if err := c.Sockets.Start(); err != nil {
+		return nil, err
+	}
+	dialer := &cosnet.DialOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		*dialer = *opts[0]
+	}
+	after := dialer.AfterReconnect
+	dialer.AfterReconnect = func(sock *cosnet.Socket) error {
+		c.resubscribe(sock)
+		if after != nil {
+			return after(sock)
+		}
+		return nil
+	}
+	sock, err := c.Sockets.Connect(address, dialer)
+	if err != nil {
+		return nil, err
+	}
+	c.sock = sock
+	return c, nil
+}
+
+// Client TCP 总线客户端，实现 cosnet.Bus 接口。
+type Client struct {
+	sock    *cosnet.Socket
+	index   uint64
+	mutex   sync.RWMutex
+	subs    map[string]map[uint64]func([]byte)
+	Sockets *cosnet.Sockets // 到总线服务器的连接管理器
+}
+
+// Socket 到总线服务器的连接。
+func (c *Client) Socket() *cosnet.Socket {
+	return c.sock
+}
+
+// Close 断开与总线服务器的连接。
+func (c *Client) Close() {
+	c.sock.Close()
+}
+
+// Publish 发布消息到频道，消息经服务器转发给所有订阅者，包括自己。
+// 参数:
+//   - channel: 频道名称
+//   - data: 消息数据
+//
+// 返回值: 连接不可用或写通道已满时返回错误
+func (c *Client) Publish(channel string, data []byte) error {
+	b, err := packet(channel, data)
+	if err != nil {
+		return err
+	}
+	return c.sock.Send(message.FlagNoreply, 0, PublishPath, b)
+}
+
+// Subscribe 订阅频道，同一频道的多个订阅者只向服务器订阅一次。
+// 参数:
+//   - channel: 频道名称
+//   - handler: 收到消息时的回调，在连接的消息处理协程中执行
+//
+// 返回值:
+//   - cancel: 取消订阅
+//   - err: 向服务器订阅失败时返回错误
+func (c *Client) Subscribe(channel string, handler func([]byte)) (cancel func(), err error) {
+	c.mutex.Lock()
+	c.index++
+	id := c.index
+	first := len(c.subs[channel]) == 0
+	if first {
+		c.subs[channel] = map[uint64]func([]byte){}
+	}
+	c.subs[channel][id] = handler
+	c.mutex.Unlock()
+	if first {
+		if err = c.sock.Send(message.FlagNoreply, 0, SubscribePath, []byte(channel)); err != nil {
+			c.remove(channel, id)
+			return nil, err
+		}
+	}
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			if c.remove(channel, id) {
+				_ = c.sock.Send(message.FlagNoreply, 0, UnsubscribePath, []byte(channel))
+			}
+		})
+	}
+	return cancel, nil
+}
+
+// remove 移除订阅，频道没有其它订阅者时返回 true
+func (c *Client) remove(channel string, id uint64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.subs[channel], id)
+	if len(c.subs[channel]) == 0 {
+		delete(c.subs, channel)
+		return true
+	}
+	return false
+}
+
+// resubscribe 重连后向服务器恢复所有订阅
+func (c *Client) resubscribe(sock *cosnet.Socket) {
+	c.mutex.RLock()
+	channels := make([]string, 0, len(c.subs))
+	for channel := range c.subs {
+		channels = append(channels, channel)
+	}
+	c.mutex.RUnlock()
+	for _, channel := range channels {
+		if err := sock.Send(message.FlagNoreply, 0, SubscribePath, []byte(channel)); err != nil {
+			logger.Alert("bus resubscribe %v error:%v", channel, err)
+		}
+	}
+}
+
+func (c *Client) receive(ctx *cosnet.Context) any {
+	channel, data, err := unpacket(ctx.Message.Body())
+	if err != nil {
+		logger.Debug("bus receive error:%v", err)
+		return nil
+	}
+	c.mutex.RLock()
+	handlers := make([]func([]byte), 0, len(c.subs[channel]))
+	for _, h := range c.subs[channel] {
+		handlers = append(handlers, h)
+	}
+	c.mutex.RUnlock()
+	for _, h := range handlers {
+		h(data)
+	}
+	return nil
+}
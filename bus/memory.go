@@ -0,0 +1,67 @@
+package bus
+
+import (
+	"sync"
+)
+
+// NewMemory 创建进程内总线，用于单进程部署多个 Sockets 实例或测试。
+func NewMemory() *Memory {
+	return &Memory{subs: map[string]map[uint64]func([]byte){}}
+}
+
+// Memory 进程内总线，Publish 在调用方协程中依次执行所有订阅回调。
+type Memory struct {
+	index uint64
+	mutex sync.RWMutex
+	subs  map[string]map[uint64]func([]byte)
+}
+
+// Publish 发布消息到频道。
+// 参数:
+//   - channel: 频道名称
+//   - data: 消息数据，所有订阅者共享，订阅者不应修改
+//
+// 返回值: 总是返回 nil
+func (b *Memory) Publish(channel string, data []byte) error {
+	b.mutex.RLock()
+	handlers := make([]func([]byte), 0, len(b.subs[channel]))
+	for _, h := range b.subs[channel] {
+		handlers = append(handlers, h)
+	}
+	b.mutex.RUnlock()
+	for _, h := range handlers {
+		h(data)
+	}
+	return nil
+}
+
+// Subscribe 订阅频道。
+// 参数:
+//   - channel: 频道名称
+//   - handler: 收到消息时的回调
+//
+// 返回值:
+//   - cancel: 取消订阅
+//   - err: 总是返回 nil
+func (b *Memory) Subscribe(channel string, handler func([]byte)) (cancel func(), err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.index++
+	id := b.index
+	if b.subs[channel] == nil {
+		b.subs[channel] = map[uint64]func([]byte){}
+	}
+	b.subs[channel][id] = handler
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+			delete(b.subs[channel], id)
+			if len(b.subs[channel]) == 0 {
+				delete(b.subs, channel)
+			}
+		})
+	}
+	return cancel, nil
+}
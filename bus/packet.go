@@ -0,0 +1,41 @@
+package bus
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// 总线服务器和客户端之间使用的路由。
+const (
+	SubscribePath   = "/bus/subscribe"   // 客户端订阅频道，包体为频道名称
+	UnsubscribePath = "/bus/unsubscribe" // 客户端取消订阅频道，包体为频道名称
+	PublishPath     = "/bus/publish"     // 客户端发布消息，包体为 packet
+	MessagePath     = "/bus/message"     // 服务器推送消息，包体为 packet，与 PublishPath 的包体相同
+)
+
+// ErrPacket 总线数据包格式错误。
+var ErrPacket = errors.New("bus packet error")
+
+// packet 编码发布的消息：频道长度(2) + 频道 + 数据
+func packet(channel string, data []byte) ([]byte, error) {
+	if len(channel) > 0xFFFF {
+		return nil, ErrPacket
+	}
+	b := make([]byte, 2+len(channel)+len(data))
+	binary.BigEndian.PutUint16(b, uint16(len(channel)))
+	copy(b[2:], channel)
+	copy(b[2+len(channel):], data)
+	return b, nil
+}
+
+// unpacket 解析发布的消息，data 引用 b 的内存
+func unpacket(b []byte) (channel string, data []byte, err error) {
+	if len(b) < 2 {
+		return "", nil, ErrPacket
+	}
+	n := 2 + int(binary.BigEndian.Uint16(b))
+	if len(b) < n {
+		return "", nil, ErrPacket
+	}
+	return string(b[2:n]), b[n:], nil
+}
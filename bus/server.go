@@ -0,0 +1,124 @@
+package bus
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// NewServer 创建 TCP 总线服务器，负责在连接到它的节点之间转发消息，调用 Listen 后开始工作。
+// 服务器使用独立的 Sockets 实例，可以在 Listen 之前修改其配置。
+func NewServer() *Server {
+	return &Server{Sockets: cosnet.New(), subs: map[string]map[uint64]*cosnet.Socket{}}
+}
+
+// Server TCP 总线服务器，可以单独部署，也可以内嵌在任意一个节点中（回环地址）。
+type Server struct {
+	mutex    sync.RWMutex
+	subs     map[string]map[uint64]*cosnet.Socket
+	listener listener.Listener
+	events   []*cosnet.Subscription
+	Sockets  *cosnet.Sockets // 节点连接管理器
+}
+
+// Listen 注册总线路由，监听地址并启动。
+// 参数 address: 监听地址，格式与 Sockets.Listen 相同，例如 "tcp://127.0.0.1:7000"。
+// 返回值: 错误信息
+func (s *Server) Listen(address string) (err error) {
+	service := s.Sockets.Service("")
+	if err = service.Register(s.subscribe, SubscribePath); err != nil {
+		return err
+	}
+	if err = service.Register(s.unsubscribe, UnsubscribePath); err != nil {
+		return err
+	}
+	if err = service.Register(s.publish, PublishPath); err != nil {
+		return err
+	}
+	s.events = append(s.events, s.Sockets.On(cosnet.EventTypeDisconnect, s.remove))
+	if s.listener, err = s.Sockets.Listen(address); err != nil {
+		return err
+	}
+	return s.Sockets.Start()
+}
+
+// Close 停止监听并断开所有节点。
+func (s *Server) Close() error {
+	for _, sub := range s.events {
+		sub.Off()
+	}
+	s.events = nil
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	s.Sockets.Range(func(sock *cosnet.Socket) bool {
+		sock.Close()
+		return true
+	})
+	return err
+}
+
+func (s *Server) subscribe(c *cosnet.Context) any {
+	channel := string(c.Message.Body())
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.subs[channel] == nil {
+		s.subs[channel] = map[uint64]*cosnet.Socket{}
+	}
+	s.subs[channel][c.Socket.Id()] = c.Socket
+	return nil
+}
+
+func (s *Server) unsubscribe(c *cosnet.Context) any {
+	channel := string(c.Message.Body())
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.subs[channel], c.Socket.Id())
+	if len(s.subs[channel]) == 0 {
+		delete(s.subs, channel)
+	}
+	return nil
+}
+
+// remove 节点断开时取消其所有订阅
+func (s *Server) remove(sock *cosnet.Socket, _ any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for channel, socks := range s.subs {
+		delete(socks, sock.Id())
+		if len(socks) == 0 {
+			delete(s.subs, channel)
+		}
+	}
+}
+
+// publish 将包体原样转发给订阅了该频道的所有节点，包括发布者自己
+func (s *Server) publish(c *cosnet.Context) any {
+	body := c.Message.Body()
+	channel, _, err := unpacket(body)
+	if err != nil {
+		logger.Debug("bus publish error:%v", err)
+		return nil
+	}
+	s.mutex.RLock()
+	socks := make([]*cosnet.Socket, 0, len(s.subs[channel]))
+	for _, sock := range s.subs[channel] {
+		socks = append(socks, sock)
+	}
+	s.mutex.RUnlock()
+	if len(socks) == 0 {
+		return nil
+	}
+	body = bytes.Clone(body)
+	for _, sock := range socks {
+		if err = sock.Send(message.FlagNoreply, 0, MessagePath, body); err != nil {
+			logger.Debug("bus relay to %v error:%v", sock.Id(), err)
+		}
+	}
+	return nil
+}
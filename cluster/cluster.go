@@ -0,0 +1,379 @@
+package cluster
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/hwcer/cosgo/binder"
+	"github.com/hwcer/cosgo/session"
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// PushPath 节点间转发消息使用的路由。
+const PushPath = "/cluster/push"
+
+// AuthPath 节点间连接认证使用的路由，设置了 Cluster.Secret 时使用。
+const AuthPath = "/cluster/auth"
+
+// ErrUserOffline 用户不在线。
+var ErrUserOffline = errors.New("user offline")
+
+// ErrSocketNotFound Socket 不存在。
+var ErrSocketNotFound = errors.New("socket not found")
+
+// ErrSecretMismatch 节点间连接的共享密钥不一致。
+var ErrSecretMismatch = errors.New("cluster secret mismatch")
+
+// envelope 节点间转发的消息。
+type envelope struct {
+	User   string       `json:"user,omitempty"`   // 目标用户，与 Socket 二选一
+	Socket uint64       `json:"socket,omitempty"` // 目标 Socket ID
+	Flag   message.Flag `json:"flag"`
+	Path   string       `json:"path"`
+	Data   []byte       `json:"data,omitempty"`
+	Raw    bool         `json:"raw,omitempty"` // Data 为原始包体，否则为 JSON，由目标 Socket 的 Binder 重新序列化
+}
+
+// credential 节点间连接的认证信息。
+type credential struct {
+	Node   uint16 `json:"node"`
+	Secret string `json:"secret"`
+}
+
+// New 创建集群节点，需要在 ss 接受连接之前调用，会设置 ss.Options.NodeId 保证 Socket ID 在集群内唯一。
+// 参数:
+//   - ss: 本节点的业务连接管理器
+//   - directory: 集群目录
+//   - id: 节点编号，集群内唯一
+//   - address: 节点间连接的监听地址，其它节点通过该地址连接本节点
+func New(ss *cosnet.Sockets, directory Directory, id uint16, address string) *Cluster {
+	ss.Options.NodeId = id
+	c := &Cluster{
+		id:        id,
+		address:   address,
+		peers:     map[uint16]*peer{},
+		users:     map[string]map[uint64]*cosnet.Socket{},
+		owners:    map[uint64]string{},
+		sockets:   ss,
+		directory: directory,
+		Link:      cosnet.New(),
+	}
+	c.Link.Options.NodeId = id
+	return c
+}
+
+// Cluster 集群节点，负责记录本节点的用户，并将发往其它节点的消息通过节点间连接转发。
+// 节点间连接默认不做认证，能够连接 Link 监听地址的任何人都可以向本节点用户推送消息，
+// 需要将监听地址限制在内网，或者设置 Secret。
+type Cluster struct {
+	id        uint16
+	address   string
+	mutex     sync.Mutex
+	peers     map[uint16]*peer
+	index     sync.Mutex
+	users     map[string]map[uint64]*cosnet.Socket // 本节点用户的连接，uuid -> Socket ID -> Socket
+	owners    map[uint64]string                    // Socket ID -> 登记时的 uuid
+	sockets   *cosnet.Sockets
+	listener  listener.Listener
+	directory Directory
+	subs      []*cosnet.Subscription
+	Link      *cosnet.Sockets // 节点间连接管理器，可以在 Start 之前修改其配置
+	Secret    string          // 节点间连接的共享密钥，所有节点必须一致，为空时不认证（Start 之前设置）
+}
+
+// peer 到其它节点的连接。
+type peer struct {
+	mutex  sync.Mutex
+	sock   *cosnet.Socket
+	closed bool // Cluster 已关闭，不再建立连接
+}
+
+// Id 节点编号。
+func (c *Cluster) Id() uint16 {
+	return c.id
+}
+
+// Start 监听节点间连接，注册节点并开始记录本节点的用户。
+// 返回值: 错误信息
+func (c *Cluster) Start() (err error) {
+	if err = cosnet.Route(c.Link, PushPath, c.receive); err != nil {
+		return err
+	}
+	if c.Secret != "" {
+		if err = cosnet.Route(c.Link, AuthPath, c.authenticate); err != nil {
+			return err
+		}
+		c.Link.Handler().Public(AuthPath)
+		c.Link.Handler().Protected(PushPath)
+	}
+	if c.listener, err = c.Link.Listen(c.address); err != nil {
+		return err
+	}
+	if err = c.Link.Start(); err != nil {
+		return err
+	}
+	if err = c.directory.Register(&Node{Id: c.id, Address: c.address}); err != nil {
+		_ = c.listener.Close()
+		return err
+	}
+	c.subs = append(c.subs,
+		c.sockets.On(cosnet.EventTypeAuthentication, c.bind),
+		c.sockets.On(cosnet.EventTypeReplaced, c.unbind),
+		c.sockets.On(cosnet.EventTypeDisconnect, c.unbind),
+	)
+	return nil
+}
+
+// Close 注销节点并关闭节点间连接。
+func (c *Cluster) Close() error {
+	for _, sub := range c.subs {
+		sub.Off()
+	}
+	c.subs = nil
+	err := c.directory.Deregister(c.id)
+	if c.listener != nil {
+		_ = c.listener.Close()
+	}
+	c.mutex.Lock()
+	peers := c.peers
+	c.peers = map[uint16]*peer{}
+	c.mutex.Unlock()
+	for _, p := range peers {
+		p.mutex.Lock()
+		p.closed = true
+		if p.sock != nil {
+			p.sock.Close()
+		}
+		p.mutex.Unlock()
+	}
+	return err
+}
+
+func (c *Cluster) bind(sock *cosnet.Socket, _ any) {
+	uuid := sock.Data().UUID()
+	if uuid == "" || sock.Type() != listener.SocketTypeServer {
+		return
+	}
+	// 同一个连接重新认证为其它用户时先注销原用户
+	if old, ok := c.owner(sock.Id()); ok && old != uuid {
+		c.unbind(sock, nil)
+	}
+	c.index.Lock()
+	socks := c.users[uuid]
+	if socks == nil {
+		socks = map[uint64]*cosnet.Socket{}
+		c.users[uuid] = socks
+	}
+	socks[sock.Id()] = sock
+	c.owners[sock.Id()] = uuid
+	c.index.Unlock()
+	if err := c.directory.Bind(uuid, c.id); err != nil {
+		logger.Alert("cluster bind user %v error:%v", uuid, err)
+	}
+}
+
+// unbind 用户在本节点已经没有其它连接时从目录中清除
+func (c *Cluster) unbind(sock *cosnet.Socket, _ any) {
+	if sock.Type() != listener.SocketTypeServer {
+		return
+	}
+	c.index.Lock()
+	uuid, ok := c.owners[sock.Id()]
+	if !ok {
+		c.index.Unlock()
+		return
+	}
+	delete(c.owners, sock.Id())
+	socks := c.users[uuid]
+	delete(socks, sock.Id())
+	empty := len(socks) == 0
+	if empty {
+		delete(c.users, uuid)
+	}
+	c.index.Unlock()
+	if !empty {
+		return
+	}
+	if err := c.directory.Unbind(uuid, c.id); err != nil {
+		logger.Alert("cluster unbind user %v error:%v", uuid, err)
+	}
+}
+
+// owner 连接登记时的用户
+func (c *Cluster) owner(id uint64) (string, bool) {
+	c.index.Lock()
+	defer c.index.Unlock()
+	uuid, ok := c.owners[id]
+	return uuid, ok
+}
+
+// local 本节点上属于用户的可用连接
+func (c *Cluster) local(uuid string) (r []*cosnet.Socket) {
+	c.index.Lock()
+	defer c.index.Unlock()
+	for _, sock := range c.users[uuid] {
+		if sock.IsReady() {
+			r = append(r, sock)
+		}
+	}
+	return
+}
+
+// SendToUser 发送消息给用户，用户在其它节点时通过节点间连接转发。
+// 参数:
+//   - uuid: 用户 UUID
+//   - flag: 消息标志
+//   - path: 消息路径
+//   - data: 消息数据，[]byte 原样发送，其它类型转发时使用 JSON 序列化，由目标 Socket 的 Binder 重新序列化
+//
+// 返回值: 用户不在线时返回 ErrUserOffline
+func (c *Cluster) SendToUser(uuid string, flag message.Flag, path string, data any) error {
+	if socks := c.local(uuid); len(socks) > 0 {
+		var err error
+		for _, sock := range socks {
+			if e := sock.Send(flag, 0, path, data); e != nil {
+				err = e
+			}
+		}
+		return err
+	}
+	node, ok, err := c.directory.Lookup(uuid)
+	if err != nil {
+		return err
+	}
+	if !ok || node == c.id {
+		return ErrUserOffline
+	}
+	return c.forward(node, &envelope{User: uuid, Flag: flag, Path: path}, data)
+}
+
+// SendToSocket 发送消息给 Socket，Socket 在其它节点时通过节点间连接转发。
+// 参数:
+//   - id: Socket ID，高 16 位为所在节点的编号
+//   - flag: 消息标志
+//   - path: 消息路径
+//   - data: 消息数据，参考 SendToUser
+//
+// 返回值: Socket 不存在时返回 ErrSocketNotFound
+func (c *Cluster) SendToSocket(id uint64, flag message.Flag, path string, data any) error {
+	node := cosnet.SocketNodeId(id)
+	if node != c.id {
+		return c.forward(node, &envelope{Socket: id, Flag: flag, Path: path}, data)
+	}
+	sock := c.sockets.Get(id)
+	if sock == nil {
+		return ErrSocketNotFound
+	}
+	return sock.Send(flag, 0, path, data)
+}
+
+// forward 将消息转发到指定节点
+func (c *Cluster) forward(node uint16, e *envelope, data any) (err error) {
+	switch v := data.(type) {
+	case []byte:
+		e.Data, e.Raw = v, true
+	case nil:
+	default:
+		if e.Data, err = binder.Json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	sock, err := c.peer(node)
+	if err != nil {
+		return err
+	}
+	return sock.Send(message.FlagNoreply, 0, PushPath, e)
+}
+
+// peer 获取到指定节点的连接，不存在时创建
+func (c *Cluster) peer(node uint16) (*cosnet.Socket, error) {
+	c.mutex.Lock()
+	p := c.peers[node]
+	if p == nil {
+		p = &peer{}
+		c.peers[node] = p
+	}
+	c.mutex.Unlock()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return nil, fmt.Errorf("cluster node %d:%w", node, net.ErrClosed)
+	}
+	if p.sock != nil && p.sock.Status() != cosnet.SocketStatusReleased {
+		return p.sock, nil
+	}
+	n, err := c.directory.Node(node)
+	if err != nil {
+		return nil, fmt.Errorf("cluster node %d:%w", node, err)
+	}
+	if c.Secret == "" {
+		p.sock, err = c.Link.Connect(n.Address)
+		return p.sock, err
+	}
+	// 断线重连后重新认证，认证包先于转发的消息发出
+	if p.sock, err = c.Link.Connect(n.Address, &cosnet.DialOptions{AfterReconnect: c.login}); err != nil {
+		return nil, err
+	}
+	if err = c.login(p.sock); err != nil {
+		p.sock.Close()
+		return nil, err
+	}
+	return p.sock, nil
+}
+
+// login 使用共享密钥认证节点间连接
+func (c *Cluster) login(sock *cosnet.Socket) error {
+	return sock.Send(message.FlagNoreply, 0, AuthPath, &credential{Node: c.id, Secret: c.Secret})
+}
+
+// authenticate 验证其它节点的共享密钥，不一致时关闭连接
+func (c *Cluster) authenticate(ctx *cosnet.Context, v *credential) (*struct{}, error) {
+	if subtle.ConstantTimeCompare([]byte(v.Secret), []byte(c.Secret)) != 1 {
+		logger.Alert("cluster node %d authenticate failed,remote:%v", v.Node, ctx.Socket.RemoteAddr())
+		ctx.Socket.Close()
+		return nil, ErrSecretMismatch
+	}
+	ctx.Socket.Authentication(session.NewData(fmt.Sprintf("node:%d", v.Node), nil))
+	return nil, nil
+}
+
+// receive 处理其它节点转发的消息，只投递给本节点的连接，不再转发
+func (c *Cluster) receive(_ *cosnet.Context, e *envelope) (*struct{}, error) {
+	var data any = e.Data
+	if !e.Raw && len(e.Data) > 0 {
+		var v any
+		if err := binder.Json.Unmarshal(e.Data, &v); err != nil {
+			return nil, err
+		}
+		data = v
+	}
+	var err error
+	if e.Socket != 0 {
+		if sock := c.sockets.Get(e.Socket); sock != nil {
+			err = sock.Send(e.Flag, 0, e.Path, data)
+		} else {
+			err = ErrSocketNotFound
+		}
+	} else {
+		socks := c.local(e.User)
+		if len(socks) == 0 {
+			err = ErrUserOffline
+		}
+		for _, sock := range socks {
+			if se := sock.Send(e.Flag, 0, e.Path, data); se != nil {
+				err = se
+			}
+		}
+	}
+	if err != nil {
+		logger.Debug("cluster receive %v error:%v", e.Path, err)
+	}
+	return nil, nil
+}
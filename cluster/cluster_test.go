@@ -0,0 +1,185 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosgo/session"
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/message"
+)
+
+type login struct {
+	Uid string `json:"uid"`
+}
+
+// startNode 启动一个带登录路由的节点
+func startNode(t *testing.T, dir Directory, id uint16, gateway, link string, setup ...func(c *Cluster)) (*cosnet.Sockets, *Cluster) {
+	ss := cosnet.New()
+	c := New(ss, dir, id, link)
+	for _, f := range setup {
+		f(c)
+	}
+	err := cosnet.Route(ss, "/login", func(ctx *cosnet.Context, req *login) (*login, error) {
+		ctx.Socket.Authentication(session.NewData(req.Uid, nil))
+		return req, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ss.Listen(gateway); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return ss, c
+}
+
+func TestForward(t *testing.T) {
+	dir := NewMemory()
+	ss1, _ := startNode(t, dir, 1, "tcp://127.0.0.1:19101", "tcp://127.0.0.1:19111")
+	_, c2 := startNode(t, dir, 2, "tcp://127.0.0.1:19102", "tcp://127.0.0.1:19112")
+
+	cl := cosnet.New()
+	got := make(chan string, 4)
+	cl.On(cosnet.EventTypeMessage, func(_ *cosnet.Socket, v any) {
+		m := v.(message.Message)
+		path, _, _ := m.Path()
+		got <- path + ":" + strings.TrimSpace(string(m.Body()))
+	})
+	sock, err := cl.Connect("tcp://127.0.0.1:19101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sock.Send(0, 1, "/login", &login{Uid: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	wait := func(want string) {
+		t.Helper()
+		select {
+		case v := <-got:
+			if v != want {
+				t.Fatalf("got %q, want %q", v, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting %q", want)
+		}
+	}
+	wait(`/login:{"uid":"u1"}`)
+
+	if node, ok, _ := dir.Lookup("u1"); !ok || node != 1 {
+		t.Fatalf("user not bound to node 1: %v %v", node, ok)
+	}
+	if err = c2.SendToUser("u1", 0, "/notice", map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	wait(`/notice:{"a":1}`)
+
+	var id uint64
+	ss1.Range(func(s *cosnet.Socket) bool {
+		id = s.Id()
+		return false
+	})
+	if cosnet.SocketNodeId(id) != 1 {
+		t.Fatalf("socket id %d not tagged with node 1", id)
+	}
+	if err = c2.SendToSocket(id, 0, "/raw", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	wait("/raw:x")
+
+	if err = c2.SendToUser("nobody", 0, "/notice", nil); err != ErrUserOffline {
+		t.Fatalf("expected ErrUserOffline, got %v", err)
+	}
+}
+
+// connectUser 连接节点并以 uid 登录，返回收到的消息
+func connectUser(t *testing.T, address, uid string) <-chan string {
+	t.Helper()
+	cl := cosnet.New()
+	got := make(chan string, 4)
+	cl.On(cosnet.EventTypeMessage, func(_ *cosnet.Socket, v any) {
+		m := v.(message.Message)
+		path, _, _ := m.Path()
+		got <- path + ":" + strings.TrimSpace(string(m.Body()))
+	})
+	sock, err := cl.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	if err = sock.Send(0, 1, "/login", &login{Uid: uid}); err != nil {
+		t.Fatal(err)
+	}
+	<-got
+	return got
+}
+
+func TestLocalIndex(t *testing.T) {
+	dir := NewMemory()
+	ss, c := startNode(t, dir, 1, "tcp://127.0.0.1:19131", "tcp://127.0.0.1:19141")
+	connectUser(t, "tcp://127.0.0.1:19131", "u1")
+	connectUser(t, "tcp://127.0.0.1:19131", "u1")
+	if n := len(c.local("u1")); n != 2 {
+		t.Fatalf("local sockets %d", n)
+	}
+	var first *cosnet.Socket
+	ss.Range(func(s *cosnet.Socket) bool {
+		first = s
+		return false
+	})
+	first.Replaced("127.0.0.1")
+	if n := len(c.local("u1")); n != 1 {
+		t.Fatalf("local sockets after replaced %d", n)
+	}
+	if _, ok, _ := dir.Lookup("u1"); !ok {
+		t.Fatal("user unbound while another socket is online")
+	}
+	ss.Range(func(s *cosnet.Socket) bool {
+		c.unbind(s, nil)
+		return true
+	})
+	if _, ok, _ := dir.Lookup("u1"); ok || len(c.local("u1")) != 0 {
+		t.Fatal("user still bound after last socket")
+	}
+}
+
+func TestSecret(t *testing.T) {
+	dir := NewMemory()
+	secret := func(v string) func(c *Cluster) {
+		return func(c *Cluster) { c.Secret = v }
+	}
+	startNode(t, dir, 1, "tcp://127.0.0.1:19151", "tcp://127.0.0.1:19161", secret("s1"))
+	_, c2 := startNode(t, dir, 2, "tcp://127.0.0.1:19152", "tcp://127.0.0.1:19162", secret("s1"))
+	_, c3 := startNode(t, dir, 3, "tcp://127.0.0.1:19153", "tcp://127.0.0.1:19163", secret("wrong"))
+	got := connectUser(t, "tcp://127.0.0.1:19151", "u1")
+
+	if err := c2.SendToUser("u1", 0, "/notice", []byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-got:
+		if v != "/notice:ok" {
+			t.Fatalf("got %q", v)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("authenticated node message not delivered")
+	}
+
+	// 密钥不一致的节点和未认证的连接无法推送消息
+	_ = c3.SendToUser("u1", 0, "/notice", []byte("wrong"))
+	raw, err := cosnet.New().Connect("tcp://127.0.0.1:19161")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	_ = raw.Send(message.FlagNoreply, 0, PushPath, &envelope{User: "u1", Path: "/notice", Data: []byte("raw"), Raw: true})
+	select {
+	case v := <-got:
+		t.Fatalf("unauthenticated message delivered %q", v)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
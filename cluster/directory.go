@@ -0,0 +1,110 @@
+package cluster
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNodeNotFound 节点不存在。
+var ErrNodeNotFound = errors.New("cluster node not found")
+
+// Node 集群节点。
+type Node struct {
+	Id      uint16 `json:"id"`      // 节点编号，与 cosnet.Config.NodeId 一致
+	Address string `json:"address"` // 节点间连接的地址
+}
+
+// Directory 集群目录，记录所有节点和用户所在的节点，可以使用 Redis、etcd 等实现。
+type Directory interface {
+	// Register 注册节点，重复注册时更新地址
+	Register(node *Node) error
+	// Deregister 注销节点，同时清除该节点上的所有用户
+	Deregister(id uint16) error
+	// Node 获取节点，不存在时返回 ErrNodeNotFound
+	Node(id uint16) (*Node, error)
+	// Nodes 获取所有节点
+	Nodes() ([]*Node, error)
+	// Bind 记录用户所在的节点
+	Bind(uuid string, node uint16) error
+	// Unbind 用户离开节点，仅当用户当前记录的节点为 node 时清除
+	Unbind(uuid string, node uint16) error
+	// Lookup 查找用户所在的节点，ok 为 false 表示用户不在线
+	Lookup(uuid string) (node uint16, ok bool, err error)
+}
+
+// NewMemory 创建内存目录，用于单进程测试或者作为其它实现的参考。
+func NewMemory() *Memory {
+	return &Memory{nodes: map[uint16]*Node{}, users: map[string]uint16{}}
+}
+
+// Memory 内存目录，多个节点共享同一个实例时才能互相发现。
+type Memory struct {
+	mutex sync.RWMutex
+	nodes map[uint16]*Node
+	users map[string]uint16
+}
+
+func (m *Memory) Register(node *Node) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v := *node
+	m.nodes[node.Id] = &v
+	return nil
+}
+
+func (m *Memory) Deregister(id uint16) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.nodes, id)
+	for uuid, node := range m.users {
+		if node == id {
+			delete(m.users, uuid)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Node(id uint16) (*Node, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	node, ok := m.nodes[id]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	v := *node
+	return &v, nil
+}
+
+func (m *Memory) Nodes() ([]*Node, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	r := make([]*Node, 0, len(m.nodes))
+	for _, node := range m.nodes {
+		v := *node
+		r = append(r, &v)
+	}
+	return r, nil
+}
+
+func (m *Memory) Bind(uuid string, node uint16) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.users[uuid] = node
+	return nil
+}
+
+func (m *Memory) Unbind(uuid string, node uint16) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if v, ok := m.users[uuid]; ok && v == node {
+		delete(m.users, uuid)
+	}
+	return nil
+}
+
+func (m *Memory) Lookup(uuid string) (uint16, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	node, ok := m.users[uuid]
+	return node, ok, nil
+}
@@ -0,0 +1,34 @@
+package cosnet
+
+import (
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+)
+
+// Codec 获取 Socket 使用的消息编解码配置，nil 表示使用 message 包的全局配置。
+func (sock *Socket) Codec() *message.Codec {
+	return sock.codec
+}
+
+// SetListenerCodec 设置通过指定监听器接入的连接使用的消息编解码配置，优先级高于 Options.Codec。
+// 需要在 Accept 之前调用，例如使用 tcp.New 创建监听器，设置后再调用 Accept。
+// 参数:
+//   - ln: 监听器实例
+//   - codec: 编解码配置，nil 表示恢复使用 Options.Codec
+func (ss *Sockets) SetListenerCodec(ln listener.Listener, codec *message.Codec) {
+	if codec != nil {
+		ss.codecs.Store(ln, codec)
+	} else {
+		ss.codecs.Delete(ln)
+	}
+}
+
+// listenerCodec 通过指定监听器接入的连接使用的消息编解码配置
+func (ss *Sockets) listenerCodec(ln listener.Listener) *message.Codec {
+	if ln != nil {
+		if v, ok := ss.codecs.Load(ln); ok {
+			return v.(*message.Codec)
+		}
+	}
+	return ss.Options.Codec
+}
@@ -1,14 +1,93 @@
 package cosnet
 
 import (
+	"context"
+	"time"
+
 	"github.com/hwcer/cosgo/binder"
+	"github.com/hwcer/cosgo/session"
 	"github.com/hwcer/cosnet/message"
 )
 
 // Context 封装了 Socket 和 Message，用于处理请求和响应。
+// Context 实现了 context.Context 接口，Socket 断开连接或超过路由超时时间时被取消，
+// 可以直接传递给数据库、RPC 等下游调用。
 type Context struct {
-	*Socket                 // 网络连接
-	Message message.Message // 当前处理的消息
+	*Socket                    // 网络连接
+	Message message.Message    // 当前处理的消息
+	data    *session.Data      // 本次请求使用的会话数据，nil 表示使用 Socket 的会话数据
+	ctx     context.Context    // 请求级上下文
+	cancel  context.CancelFunc // 请求结束时释放 ctx
+}
+
+// newContext 创建请求上下文，继承 Socket 的连接级上下文。
+// 参数:
+//   - sock: 网络连接
+//   - msg: 当前处理的消息
+//   - timeout: 路由超时时间，0 表示不限制
+func newContext(sock *Socket, msg message.Message, timeout time.Duration) *Context {
+	c := &Context{Socket: sock, Message: msg}
+	parent := sock.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	if timeout > 0 {
+		c.ctx, c.cancel = context.WithTimeout(parent, timeout)
+	} else {
+		c.ctx, c.cancel = context.WithCancel(parent)
+	}
+	return c
+}
+
+// release 请求处理完毕，取消请求级上下文。
+func (this *Context) release() {
+	if this.cancel != nil {
+		this.cancel()
+	}
+}
+
+// Context 返回请求级 context.Context。
+func (this *Context) Context() context.Context {
+	if this.ctx == nil {
+		return context.Background()
+	}
+	return this.ctx
+}
+
+// Deadline 实现 context.Context 接口。
+func (this *Context) Deadline() (time.Time, bool) {
+	return this.Context().Deadline()
+}
+
+// Done 实现 context.Context 接口，Socket 断开、超时或请求结束时关闭。
+func (this *Context) Done() <-chan struct{} {
+	return this.Context().Done()
+}
+
+// Err 实现 context.Context 接口。
+func (this *Context) Err() error {
+	return this.Context().Err()
+}
+
+// Value 实现 context.Context 接口，获取请求级数据。
+func (this *Context) Value(key any) any {
+	return this.Context().Value(key)
+}
+
+// WithValue 设置请求级数据，仅在本次请求内有效。
+// 参数:
+//   - key: 键
+//   - val: 值
+func (this *Context) WithValue(key, val any) {
+	this.ctx = context.WithValue(this.Context(), key, val)
+}
+
+// Data 本次请求的会话数据，网关转发的请求为客户端的会话数据，其它请求为 Socket 的会话数据。
+func (this *Context) Data() *session.Data {
+	if this.data != nil {
+		return this.data
+	}
+	return this.Socket.Data()
 }
 
 // Path 获取消息的路径和查询参数。
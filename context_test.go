@@ -0,0 +1,66 @@
+package cosnet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestContextCancelOnDisconnect(t *testing.T) {
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	_, address := testServer(t, func(ss *Sockets) {
+		ss.Options.HandleMode = HandleModeSocket
+		_ = ss.Service().Register(func(c *Context) any {
+			close(started)
+			select {
+			case <-c.Done():
+				result <- c.Err()
+			case <-time.After(2 * time.Second):
+				result <- nil
+			}
+			return nil
+		}, "/wait")
+	})
+	sock, _ := testClient(t, address)
+	_ = sock.Send(0, 1, "/wait", nil)
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler not started")
+	}
+	// Close 在下一次心跳时断开连接
+	sock.Close()
+	sock.sockets.Heartbeat(1)
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("context error %v", err)
+	}
+}
+
+func TestContextRouteTimeout(t *testing.T) {
+	result := make(chan error, 1)
+	_, address := testServer(t, func(ss *Sockets) {
+		_ = ss.Service().Register(func(c *Context) any {
+			<-c.Done()
+			result <- c.Err()
+			return c.Err()
+		}, "/slow")
+		ss.Handler().SetTimeout(20*time.Millisecond, "/slow")
+	})
+	sock, replies := testClient(t, address)
+	_ = sock.Send(0, 1, "/slow", nil)
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("context error %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("route timeout not applied")
+	}
+	// 返回 c.Err() 时回复 ErrorCodeTimeout
+	r := testReceive(t, replies)
+	if e := testError(t, r); e == nil || e.Code != ErrorCodeTimeout {
+		t.Fatalf("timeout reply %+v", r)
+	}
+}
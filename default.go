@@ -5,6 +5,7 @@ import (
 
 	"github.com/hwcer/cosgo/registry"
 	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
 )
 
 // Default 是默认的 Sockets 实例。
@@ -29,8 +30,27 @@ func Start() error {
 // 参数:
 //   - eventType: 事件类型
 //   - eventFunc: 事件处理函数
-func On(eventType EventType, eventFunc func(*Socket, any)) {
-	Default.On(eventType, eventFunc)
+//
+// 返回值: 监听句柄，调用 Off 取消监听
+func On(eventType EventType, eventFunc func(*Socket, any)) *Subscription {
+	return Default.On(eventType, eventFunc)
+}
+
+// OnAsync 注册异步事件处理函数到默认实例。
+// 参数:
+//   - eventType: 事件类型
+//   - eventFunc: 事件处理函数
+//   - queue: 可选，队列长度
+//
+// 返回值: 监听句柄，调用 Off 取消监听
+func OnAsync(eventType EventType, eventFunc func(*Socket, any), queue ...int32) *Subscription {
+	return Default.OnAsync(eventType, eventFunc, queue...)
+}
+
+// Use 添加全局中间件到默认实例。
+// 参数 middleware: 中间件列表。
+func Use(middleware ...HandlerMiddleware) {
+	Default.Use(middleware...)
 }
 
 // Get 通过 Socket ID 从默认实例获取 Socket。
@@ -98,12 +118,31 @@ func Accept(ln listener.Listener) {
 }
 
 // Connect 连接服务器（默认实例）。
-// 参数 address: 服务器地址。
+// 参数:
+//   - address: 服务器地址
+//   - opts: 连接选项，可选
+//
 // 返回值:
 //   - socket: 连接的 Socket 实例
 //   - err: 错误信息
-func Connect(address string) (socket *Socket, err error) {
-	return Default.Connect(address)
+func Connect(address string, opts ...*DialOptions) (socket *Socket, err error) {
+	return Default.Connect(address, opts...)
+}
+
+// Broadcast 广播消息（默认实例）。
+// 参数:
+//   - m: 要广播的消息，调用方负责回收
+//   - filter: 过滤函数，为 nil 时发送给所有 Socket
+func Broadcast(m message.Message, filter func(*Socket) bool) {
+	Default.Broadcast(m, filter)
+}
+
+// BroadcastGroup 广播消息给加入了分组的 Socket（默认实例）。
+// 参数:
+//   - group: 分组名称
+//   - m: 要广播的消息，调用方负责回收
+func BroadcastGroup(group string, m message.Message) {
+	Default.BroadcastGroup(group, m)
 }
 
 // Heartbeat 对默认实例中的所有连接执行心跳检查。
@@ -0,0 +1,105 @@
+package cosnet
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/tcp"
+	"github.com/hwcer/cosnet/udp"
+	"github.com/hwcer/cosnet/wss"
+)
+
+// DialOptions 客户端连接选项，断线重连时继续使用。
+type DialOptions struct {
+	Timeout   time.Duration // 连接超时时间，0 表示使用 Options.ClientDialTimeout
+	TLSConfig *tls.Config   // TLS 配置，wss 和 tls（tcps）使用，双向认证时设置 Certificates
+	Header    http.Header   // WebSocket 握手时附加的请求头
+	// BeforeReconnect 每次尝试重连前调用，参数 attempt 为第几次尝试，可以调用 Socket.Reconnect 更换服务器地址；
+	// 返回错误时放弃重连，触发 EventTypeReconnectFailed 并销毁 Socket
+	BeforeReconnect func(sock *Socket, attempt int32) error
+	// AfterReconnect 重连成功后、断线期间积压的消息恢复发送前调用，可以在这里重新登录、恢复订阅；
+	// 调用期间可以正常收发消息，返回错误时放弃重连，触发 EventTypeReconnectFailed 并关闭连接
+	AfterReconnect func(sock *Socket) error
+}
+
+// ReconnectError 断线重连失败的原因，EventTypeReconnectFailed 事件的参数。
+type ReconnectError struct {
+	Attempt int32  // 已经尝试的次数
+	Address string // 最后一次尝试的服务器地址
+	Err     error  // 最后一次失败的原因
+}
+
+// Error 实现 error 接口。
+func (e *ReconnectError) Error() string {
+	return fmt.Sprintf("reconnect %v failed after %d attempts:%v", e.Address, e.Attempt, e.Err)
+}
+
+// Unwrap 返回最后一次失败的原因。
+func (e *ReconnectError) Unwrap() error {
+	return e.Err
+}
+
+// dial 按地址的 scheme 选择传输层连接服务器，支持 Listen 接受的所有 scheme。
+// 地址示例: tcp://127.0.0.1:3000、tls://example.com:3443、ws://127.0.0.1:8080/ws、wss://example.com/ws、udp://127.0.0.1:3001、
+// unix:///tmp/cosnet.sock、unix://@cosnet（Linux 抽象命名空间）
+// 参数:
+//   - address: 服务器地址，没有 scheme 时使用 tcp
+//   - opts: 连接选项，可以为 nil
+func (ss *Sockets) dial(address string, opts *DialOptions) (listener.Conn, error) {
+	network, addr := "tcp", address
+	if i := strings.Index(address, "://"); i >= 0 {
+		network, addr = strings.ToLower(address[:i]), address[i+3:]
+	}
+	if opts == nil {
+		opts = &DialOptions{}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = time.Duration(ss.Options.ClientDialTimeout) * time.Millisecond
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return tcp.Dial(network, addr, timeout)
+	case "tls", "tls4", "tls6", "tcps", "tcps4", "tcps6":
+		return tcp.DialTLS(tlsNetwork(network), addr, timeout, opts.TLSConfig)
+	case "ws":
+		return wss.Dial("ws://"+addr, timeout, nil, opts.Header, ss.Options.WSS)
+	case "wss", "wss4", "wss5", "wss6":
+		return wss.Dial("wss://"+addr, timeout, opts.TLSConfig, opts.Header, ss.Options.WSS)
+	case "udp", "udp4", "udp6":
+		return udp.DialWithConfig(network, addr, timeout, ss.Options.UDP)
+	default:
+		return nil, errors.New("address scheme unknown")
+	}
+}
+
+// tlsNetwork 将 tls、tcps scheme 转换为 TCP 网络类型
+func tlsNetwork(scheme string) string {
+	switch scheme[len(scheme)-1] {
+	case '4':
+		return "tcp4"
+	case '6':
+		return "tcp6"
+	default:
+		return "tcp"
+	}
+}
+
+// backoff 第 attempt 次重连失败后的等待时间，ClientReconnectTime * attempt，
+// 不超过 ClientReconnectMaxDelay，并按 ClientReconnectJitter 随机浮动。
+func (ss *Sockets) backoff(attempt int32) time.Duration {
+	delay := int64(ss.Options.ClientReconnectTime) * int64(attempt)
+	if ss.Options.ClientReconnectMaxDelay > 0 && delay > int64(ss.Options.ClientReconnectMaxDelay) {
+		delay = int64(ss.Options.ClientReconnectMaxDelay)
+	}
+	if jitter := delay * int64(ss.Options.ClientReconnectJitter) / 100; jitter > 0 {
+		delay += rand.Int64N(2*jitter+1) - jitter
+	}
+	return time.Duration(delay) * time.Millisecond
+}
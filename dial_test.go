@@ -0,0 +1,117 @@
+package cosnet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/tcp"
+	"github.com/hwcer/cosnet/udp"
+	"github.com/hwcer/cosnet/wss"
+)
+
+// testCertificate 生成自签名证书
+func testCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestDialScheme(t *testing.T) {
+	cert := testCertificate(t)
+	listen := map[string]func(t *testing.T) (listener.Listener, string){
+		"tcp": func(t *testing.T) (listener.Listener, string) {
+			ln, err := tcp.New("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ln, "tcp://" + ln.Addr().String()
+		},
+		"tls": func(t *testing.T) (listener.Listener, string) {
+			ln, err := tcp.NewTLS("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ln, "tls://" + ln.Addr().String()
+		},
+		"ws": func(t *testing.T) (listener.Listener, string) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := &http.Server{}
+			ln := wss.NewListener(srv, "")
+			go func() {
+				_ = srv.Serve(l)
+			}()
+			t.Cleanup(func() {
+				_ = srv.Close()
+			})
+			return ln, "ws://" + l.Addr().String() + "/"
+		},
+		"udp": func(t *testing.T) (listener.Listener, string) {
+			ln, err := udp.New("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ln, "udp://" + ln.Addr().String()
+		},
+		"unix": func(t *testing.T) (listener.Listener, string) {
+			path := filepath.Join(t.TempDir(), "cosnet.sock")
+			ln, err := tcp.NewUnix(path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ln, "unix://" + path
+		},
+	}
+	for scheme, f := range listen {
+		t.Run(scheme, func(t *testing.T) {
+			ss := New()
+			_ = ss.Service().Register(func(c *Context) any {
+				return []byte("pong")
+			}, "/ping")
+			ln, address := f(t)
+			ss.Accept(ln)
+			t.Cleanup(func() {
+				_ = ln.Close()
+			})
+			opts := &DialOptions{TLSConfig: &tls.Config{InsecureSkipVerify: true}}
+			sock, replies := testClientWithOptions(t, address, opts)
+			_ = sock.Send(0, 1, "/ping", nil)
+			if r := testReceive(t, replies); string(r.body) != "pong" {
+				t.Fatalf("reply %+v", r)
+			}
+		})
+	}
+}
+
+func TestDialSchemeUnknown(t *testing.T) {
+	if _, err := New().dial("quic://127.0.0.1:1", nil); err == nil {
+		t.Fatal("unknown scheme dialed")
+	}
+}
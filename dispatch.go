@@ -0,0 +1,155 @@
+package cosnet
+
+import (
+	"context"
+	"hash/fnv"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/hwcer/cosgo/scc"
+	"github.com/hwcer/cosnet/message"
+)
+
+// HandleMode 定义消息处理模式。
+type HandleMode int8
+
+// 消息处理模式常量。
+const (
+	HandleModeInline HandleMode = iota // 在读协程中直接处理（默认），处理期间该 Socket 不再读取消息
+	HandleModeSocket                   // 每个 Socket 一个有序工作协程，单 Socket 内顺序处理
+	HandleModePool                     // 全局共享的有界工作池，并行处理，不保证顺序
+	HandleModeKeyed                    // 按用户 UUID 分片串行执行（未认证时按 Socket ID），同一用户跨 Socket 有序
+)
+
+// dispatchTask 等待处理的消息。
+type dispatchTask struct {
+	sock *Socket
+	msg  message.Message
+}
+
+// dispatcher 共享工作池，HandleModePool 和 HandleModeKeyed 模式使用。
+// HandleModePool: 一个队列，多个工作协程；HandleModeKeyed: 多个队列，每个队列一个工作协程。
+type dispatcher struct {
+	queues []chan dispatchTask
+}
+
+// newDispatcher 创建并启动工作池。
+// 参数:
+//   - mode: 消息处理模式
+//   - size: 工作协程数量，0 表示使用 CPU 核数
+//   - queue: 每个队列的长度
+func newDispatcher(mode HandleMode, size, queue int32) *dispatcher {
+	if size <= 0 {
+		size = int32(runtime.NumCPU())
+	}
+	d := &dispatcher{}
+	if mode == HandleModeKeyed {
+		d.queues = make([]chan dispatchTask, size)
+		for i := range d.queues {
+			d.queues[i] = make(chan dispatchTask, queue)
+			scc.CGO(d.worker(d.queues[i]))
+		}
+	} else {
+		d.queues = []chan dispatchTask{make(chan dispatchTask, queue)}
+		for i := int32(0); i < size; i++ {
+			scc.CGO(d.worker(d.queues[0]))
+		}
+	}
+	return d
+}
+
+func (d *dispatcher) worker(queue chan dispatchTask) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-queue:
+				t.sock.handle(t.sock, t.msg)
+				message.Release(t.msg)
+				atomic.AddInt32(&t.sock.queued, -1)
+			}
+		}
+	}
+}
+
+// push 将消息放入队列，队列已满时返回 false。
+func (d *dispatcher) push(sock *Socket, msg message.Message) bool {
+	queue := d.queues[0]
+	if n := len(d.queues); n > 1 {
+		queue = d.queues[sock.dispatchKey()%uint64(n)]
+	}
+	atomic.AddInt32(&sock.queued, 1)
+	select {
+	case queue <- dispatchTask{sock: sock, msg: msg}:
+		return true
+	default:
+		atomic.AddInt32(&sock.queued, -1)
+		return false
+	}
+}
+
+// dispatchKey HandleModeKeyed 模式下的分片键，仅在读协程中调用。
+// 登录后分片键从 Socket ID 切换为用户 UUID，该 Socket 还有消息在原分片中等待处理时继续使用原分片，
+// 处理完毕后再切换，保证单 Socket 内的顺序。
+func (sock *Socket) dispatchKey() uint64 {
+	if atomic.LoadInt32(&sock.queued) > 0 {
+		return sock.shard
+	}
+	sock.shard = sock.id
+	if uuid := sock.Data().UUID(); uuid != "" {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(uuid))
+		sock.shard = h.Sum64()
+	}
+	return sock.shard
+}
+
+// dispatch 按 Options.HandleMode 处理消息，调用后消息的所有权转移给处理流程，由其负责回收。
+// 队列已满时丢弃消息并触发 EventTypeOverload 事件。
+func (sock *Socket) dispatch(msg message.Message) {
+	var ok bool
+	switch sock.sockets.Options.HandleMode {
+	case HandleModeSocket:
+		select {
+		case sock.chandle <- msg:
+			ok = true
+		default:
+		}
+	case HandleModePool, HandleModeKeyed:
+		ok = sock.sockets.dispatcher().push(sock, msg)
+	default:
+		sock.handle(sock, msg)
+		message.Release(msg)
+		return
+	}
+	if !ok {
+		sock.Emit(EventTypeOverload, messagePath(msg))
+		_ = sock.replyError(msg, NewError(ErrorCodeOverload, "server overload"), false)
+		message.Release(msg)
+	}
+}
+
+// handleMsg HandleModeSocket 模式下每个 Socket 的工作协程。
+func (sock *Socket) handleMsg(ctx context.Context) {
+	stop := sock.stop
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case msg := <-sock.chandle:
+			sock.handle(sock, msg)
+			message.Release(msg)
+		}
+	}
+}
+
+// dispatcher 获取共享工作池，首次使用时创建。
+func (ss *Sockets) dispatcher() *dispatcher {
+	ss.dispatchOnce.Do(func() {
+		ss.dispatch = newDispatcher(ss.Options.HandleMode, ss.Options.HandleWorkerSize, ss.Options.HandleQueueSize)
+	})
+	return ss.dispatch
+}
@@ -0,0 +1,89 @@
+package cosnet
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosgo/session"
+)
+
+func TestHandleMode(t *testing.T) {
+	modes := map[string]HandleMode{
+		"inline": HandleModeInline,
+		"socket": HandleModeSocket,
+		"pool":   HandleModePool,
+		"keyed":  HandleModeKeyed,
+	}
+	for name, mode := range modes {
+		t.Run(name, func(t *testing.T) {
+			_, address := testServer(t, func(ss *Sockets) {
+				ss.Options.HandleMode = mode
+				ss.Options.HandleWorkerSize = 4
+				_ = ss.Service().Register(func(c *Context) any {
+					return append([]byte(nil), c.Message.Body()...)
+				}, "/echo")
+			})
+			sock, replies := testClient(t, address)
+			const total = 50
+			for i := 0; i < total; i++ {
+				if err := sock.Send(0, int32(i+1), "/echo", []byte(strconv.Itoa(i))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			seen := make(map[string]bool, total)
+			for i := 0; i < total; i++ {
+				r := testReceive(t, replies)
+				seen[string(r.body)] = true
+				// 单 Socket 内顺序处理的模式必须按发送顺序回复
+				if mode != HandleModePool && string(r.body) != strconv.Itoa(i) {
+					t.Fatalf("reply %d out of order:%s", i, r.body)
+				}
+			}
+			if len(seen) != total {
+				t.Fatalf("replies %d", len(seen))
+			}
+		})
+	}
+}
+
+// TestHandleModeKeyedLogin 登录后分片键切换为用户 UUID，切换前排队的消息仍然先处理
+func TestHandleModeKeyedLogin(t *testing.T) {
+	_, address := testServer(t, func(ss *Sockets) {
+		ss.Options.HandleMode = HandleModeKeyed
+		ss.Options.HandleWorkerSize = 64
+		service := ss.Service()
+		_ = service.Register(func(c *Context) any {
+			time.Sleep(2 * time.Millisecond) // 原分片中排队的消息处理较慢，切换分片过早时会被之后的消息超过
+			return append([]byte(nil), c.Message.Body()...)
+		}, "/echo")
+		_ = service.Register(func(c *Context) any {
+			c.Socket.Authentication(session.NewData("keyed", nil))
+			return append([]byte(nil), c.Message.Body()...)
+		}, "/login")
+	})
+	sock, replies := testClient(t, address)
+	send := func(from, to int) {
+		for i := from; i < to; i++ {
+			path := "/echo"
+			if i == 0 {
+				path = "/login"
+			}
+			if err := sock.Send(0, int32(i+1), path, []byte(strconv.Itoa(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	const total = 40
+	send(0, total/2)
+	if r := testReceive(t, replies); string(r.body) != "0" {
+		t.Fatalf("login reply %s", r.body)
+	}
+	// 登录完成后原分片中仍有排队的消息
+	send(total/2, total)
+	for i := 1; i < total; i++ {
+		if r := testReceive(t, replies); string(r.body) != strconv.Itoa(i) {
+			t.Fatalf("reply %d out of order:%s", i, r.body)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package cosnet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hwcer/cosgo/binder"
+	"github.com/hwcer/cosgo/values"
+	"github.com/hwcer/cosnet/message"
+)
+
+// ErrAuthenticationRequired 未认证的 Socket 调用需要身份认证的路由。
+var ErrAuthenticationRequired = errors.New("authentication required")
+
+// ErrRouteNotFound 路由不存在。
+var ErrRouteNotFound = errors.New("route not found")
+
+// 标准错误码，业务层可以使用其它数值自定义错误码。
+const (
+	ErrorCodeBadRequest   int32 = 400                            // 请求格式错误，路径或包体无法解析
+	ErrorCodeUnauthorized int32 = 401                            // 未认证调用需要身份认证的路由
+	ErrorCodeNotFound     int32 = 404                            // 路由不存在
+	ErrorCodeTimeout      int32 = 408                            // 处理超时
+	ErrorCodeInternal     int32 = 500                            // 服务器内部错误，handler panic
+	ErrorCodeBadGateway   int32 = 502                            // 网关无法转发到后端服务
+	ErrorCodeOverload     int32 = 503                            // 处理队列已满
+	ErrorCodeDefault            = values.MessageErrorCodeDefault // handler 返回的普通 error
+)
+
+// ErrorBinder 错误包使用的序列化方式，与消息魔数无关，保证客户端能够统一解析。
+var ErrorBinder binder.Binder = binder.Json
+
+// Error 标准错误包，handler 返回 error、panic、路由不存在和请求格式错误时回传给客户端。
+// 错误包带有 message.FlagConfirm | message.FlagError 标记，包体使用 ErrorBinder 序列化。
+type Error struct {
+	Code    int32  `json:"code"`              // 错误码
+	Message string `json:"message"`           // 错误信息
+	Details any    `json:"details,omitempty"` // 附加信息，可选
+}
+
+// Error 实现 error 接口。
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// WithDetails 设置附加信息。
+// 参数 details: 附加信息。
+// 返回值: 错误本身，便于链式调用。
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+// NewError 创建标准错误。
+// 参数:
+//   - code: 错误码
+//   - format: 错误信息或格式字符串
+//   - args: 格式参数
+func NewError(code int32, format any, args ...any) *Error {
+	return &Error{Code: code, Message: values.Sprintf(format, args...)}
+}
+
+// ToError 将任意 error 转换为标准错误。
+// 已经是 *Error 时原样返回，*values.Message 保留其错误码，超时映射为 ErrorCodeTimeout。
+// 参数 err: 错误信息。
+// 返回值: 标准错误。
+func ToError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var m *values.Message
+	if errors.As(err, &m) {
+		code := m.Code
+		if code == 0 {
+			code = ErrorCodeDefault
+		}
+		return &Error{Code: code, Message: m.String()}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Code: ErrorCodeTimeout, Message: err.Error()}
+	}
+	if errors.Is(err, ErrAuthenticationRequired) {
+		return &Error{Code: ErrorCodeUnauthorized, Message: err.Error()}
+	}
+	if errors.Is(err, ErrRouteNotFound) {
+		return &Error{Code: ErrorCodeNotFound, Message: err.Error()}
+	}
+	return &Error{Code: ErrorCodeDefault, Message: err.Error()}
+}
+
+// ParseError 从收到的消息中解析标准错误。
+// 参数 m: 收到的消息。
+// 返回值: 标准错误，不是错误包时返回 nil。
+func ParseError(m message.Message) *Error {
+	if !m.Flag().Has(message.FlagError) {
+		return nil
+	}
+	e := &Error{}
+	if err := ErrorBinder.Unmarshal(m.Body(), e); err != nil {
+		e.Code = ErrorCodeDefault
+		e.Message = string(m.Body())
+	}
+	return e
+}
@@ -0,0 +1,77 @@
+package cosnet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hwcer/cosgo/values"
+	"github.com/hwcer/cosnet/message"
+)
+
+func TestReplyValuesMessage(t *testing.T) {
+	_, address := testServer(t, func(ss *Sockets) {
+		service := ss.Service()
+		_ = service.Register(func(c *Context) any {
+			return values.Parse(map[string]int{"n": 1})
+		}, "/ok")
+		_ = service.Register(func(c *Context) any {
+			return values.Errorf(403, "forbidden")
+		}, "/fail")
+	})
+	sock, replies := testClient(t, address)
+
+	_ = sock.Send(0, 1, "/ok", nil)
+	r := testReceive(t, replies)
+	if r.flag.Has(message.FlagError) {
+		t.Fatalf("values.Parse reply sent as error:%s", r.body)
+	}
+	m := &values.Message{}
+	if err := json.Unmarshal(r.body, m); err != nil || m.Code != 0 || fmt.Sprint(m.Data) != "map[n:1]" {
+		t.Fatalf("reply %s,err:%v", r.body, err)
+	}
+
+	_ = sock.Send(0, 2, "/fail", nil)
+	if e := testError(t, testReceive(t, replies)); e == nil || e.Code != 403 || e.Message != "forbidden" {
+		t.Fatalf("values.Errorf reply %+v", e)
+	}
+}
+
+func TestErrorRoundTrip(t *testing.T) {
+	_, address := testServer(t, func(ss *Sockets) {
+		_ = ss.Service().Register(func(c *Context) any {
+			return NewError(ErrorCodeBadGateway, "backend %s", "down").WithDetails(map[string]string{"node": "a"})
+		}, "/fail")
+	})
+	sock, replies := testClient(t, address)
+	_ = sock.Send(0, 1, "/fail", nil)
+	r := testReceive(t, replies)
+	if r.index != 1 {
+		t.Fatalf("reply index %d", r.index)
+	}
+	e := testError(t, r)
+	if e == nil || e.Code != ErrorCodeBadGateway || e.Message != "backend down" || fmt.Sprint(e.Details) != "map[node:a]" {
+		t.Fatalf("error reply %+v", e)
+	}
+}
+
+func TestToError(t *testing.T) {
+	cases := []struct {
+		err  error
+		code int32
+	}{
+		{NewError(ErrorCodeOverload, "busy"), ErrorCodeOverload},
+		{values.Errorf(0, "failed"), values.MessageErrorCodeDefault},
+		{fmt.Errorf("wrap:%w", context.DeadlineExceeded), ErrorCodeTimeout},
+		{ErrAuthenticationRequired, ErrorCodeUnauthorized},
+		{ErrRouteNotFound, ErrorCodeNotFound},
+		{errors.New("other"), ErrorCodeDefault},
+	}
+	for _, v := range cases {
+		if e := ToError(v.err); e.Code != v.code {
+			t.Errorf("ToError(%v) code %d, want %d", v.err, e.Code, v.code)
+		}
+	}
+}
@@ -1,18 +1,37 @@
 package cosnet
 
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hwcer/cosgo/scc"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
 // EventType 定义事件类型。
 type EventType uint8
 
 // 事件类型常量定义。
 const (
-	EventTypeError          EventType = iota + 1 // 系统级别错误事件,参数: Socket||nil,错误信息
-	EventTypeHeartbeat                           // 心跳事件,参数:Socket,心跳计数增量
-	EventTypeMessage                             // 所有未注册的消息事件,参数:Socket,消息内容
-	EventTypeConnected                           // 连接成功事件,参数:Socket,nil
-	EventTypeReconnected                         // 断线重连事件,参数:Socket,nil
-	EventTypeDisconnect                          // 断开连接事件,参数:Socket,nil
-	EventTypeAuthentication                      // 身份认证事件,参数:Socket,是否重连
-	EventTypeReplaced                            // 被顶号事件,参数:Socket,新Socket ip
+	EventTypeError            EventType = iota + 1 // 系统级别错误事件,参数: Socket||nil,错误信息
+	EventTypeHeartbeat                             // 心跳事件,参数:Socket,心跳计数增量
+	EventTypeMessage                               // 所有未注册的消息事件,参数:Socket,消息内容
+	EventTypeConnected                             // 连接成功事件,参数:Socket,nil
+	EventTypeReconnected                           // 断线重连事件,参数:Socket,nil
+	EventTypeDisconnect                            // 断开连接事件,参数:Socket,nil
+	EventTypeAuthentication                        // 身份认证事件,参数:Socket,是否重连
+	EventTypeReplaced                              // 被顶号事件,参数:Socket,新Socket ip
+	EventTypeUnauthorized                          // 未认证访问事件,参数:Socket,被拒绝的路由path(认证超时关闭时为nil)
+	EventTypeOverload                              // 处理队列已满丢弃消息事件,参数:Socket,消息path
+	EventTypeReleased                              // Socket 销毁事件,参数:Socket,nil
+	EventTypeReconnectFailed                       // 断线重连失败事件,参数:Socket,*ReconnectError
+	EventTypeMessageDropped                        // 写通道已满丢弃消息事件,参数:Socket,消息path
+	EventTypeHeartbeatTimeout                      // 客户端心跳超时事件,参数:Socket,距离上次心跳回应的时长time.Duration
+	EventTypeReconnecting                          // 断线重连尝试事件,参数:Socket,第几次尝试int32
+	EventTypeRejected                              // 连接被准入检查拒绝事件,参数:nil,*RejectError
 )
 
 // EventsFunc 定义事件处理函数类型。
@@ -20,3 +39,163 @@ const (
 //   - *Socket: 触发事件的 Socket
 //   - any: 事件附加数据
 type EventsFunc func(*Socket, any)
+
+// eventArgs 异步监听器队列中的事件参数。
+type eventArgs struct {
+	socket *Socket
+	attach any
+}
+
+// Subscription 事件监听句柄，调用 Off 取消监听。
+type Subscription struct {
+	id      uint64
+	event   EventType
+	handle  EventsFunc
+	queue   chan eventArgs // 异步监听器的事件队列，同步监听器为 nil
+	emitter *emitter
+	closed  atomic.Bool
+}
+
+// Off 取消监听，可以重复调用。
+// 异步监听器队列中尚未处理的事件会被丢弃。
+func (sub *Subscription) Off() {
+	if !sub.closed.CompareAndSwap(false, true) {
+		return
+	}
+	sub.emitter.remove(sub)
+	if sub.queue != nil {
+		close(sub.queue)
+	}
+}
+
+// call 执行监听函数，隔离 panic，避免影响触发事件的读写协程。
+func (sub *Subscription) call(s *Socket, v any) {
+	defer func() {
+		if e := recover(); e != nil {
+			logger.Alert("event listener panic,event:%d,error:%v", sub.event, e)
+		}
+	}()
+	sub.handle(s, v)
+}
+
+// push 投递事件，异步监听器放入队列，队列已满时丢弃。
+func (sub *Subscription) push(s *Socket, v any) {
+	if sub.queue == nil {
+		sub.call(s, v)
+		return
+	}
+	defer func() {
+		_ = recover() // 并发 Off 时队列可能已关闭
+	}()
+	// 消息在同步处理结束后会被回收，异步监听器需要持有副本
+	if m, ok := v.(message.Message); ok {
+		c, err := cloneMessage(m)
+		if err != nil {
+			logger.Alert("event listener clone message error,event:%d,error:%v", sub.event, err)
+			return
+		}
+		v = c
+	}
+	select {
+	case sub.queue <- eventArgs{socket: s, attach: v}:
+	default:
+		logger.Alert("event listener queue full,event:%d,drop event", sub.event)
+	}
+}
+
+// cloneMessage 复制消息，副本不来自消息池，由 GC 回收。
+func cloneMessage(m message.Message) (message.Message, error) {
+	buf := &bytes.Buffer{}
+	if _, err := m.Bytes(buf, true); err != nil {
+		return nil, err
+	}
+	c := message.Options.New()
+	c.SetCodec(m.Codec())
+	if err := c.Reset(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// worker 异步监听器的工作协程，按投递顺序依次处理。
+func (sub *Subscription) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case args, ok := <-sub.queue:
+			if !ok {
+				return
+			}
+			sub.call(args.socket, args.attach)
+		}
+	}
+}
+
+// emitter 并发安全的事件监听器集合。
+// 注册和取消时复制整个映射（copy-on-write），触发事件时无锁读取快照。
+type emitter struct {
+	index     uint64
+	mutex     sync.Mutex
+	listeners atomic.Pointer[map[EventType][]*Subscription]
+}
+
+// add 添加监听器。
+// 参数:
+//   - e: 事件类型
+//   - f: 事件处理函数
+//   - queue: 异步队列长度，0 表示同步监听
+func (em *emitter) add(e EventType, f EventsFunc, queue int32) *Subscription {
+	sub := &Subscription{event: e, handle: f, emitter: em}
+	if queue > 0 {
+		sub.queue = make(chan eventArgs, queue)
+		scc.CGO(sub.worker)
+	}
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	em.index++
+	sub.id = em.index
+	dict := em.clone()
+	dict[e] = append(dict[e], sub)
+	em.listeners.Store(&dict)
+	return sub
+}
+
+// remove 移除监听器。
+func (em *emitter) remove(sub *Subscription) {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	dict := em.clone()
+	var subs []*Subscription
+	for _, v := range dict[sub.event] {
+		if v.id != sub.id {
+			subs = append(subs, v)
+		}
+	}
+	dict[sub.event] = subs
+	em.listeners.Store(&dict)
+}
+
+// clone 复制当前监听器映射，调用前必须持有 mutex。
+func (em *emitter) clone() map[EventType][]*Subscription {
+	dict := make(map[EventType][]*Subscription)
+	if p := em.listeners.Load(); p != nil {
+		for k, v := range *p {
+			dict[k] = append([]*Subscription(nil), v...)
+		}
+	}
+	return dict
+}
+
+// get 获取事件的监听器快照。
+func (em *emitter) get(e EventType) []*Subscription {
+	if p := em.listeners.Load(); p != nil {
+		return (*p)[e]
+	}
+	return nil
+}
+
+// count 获取事件的监听器数量。
+func (em *emitter) count(e EventType) int {
+	return len(em.get(e))
+}
@@ -0,0 +1,70 @@
+package cosnet
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosnet/message"
+)
+
+func TestOnAsyncMessage(t *testing.T) {
+	const total = 3
+	gate := make(chan struct{})
+	received := make(chan string, total)
+	_, address := testServer(t, func(ss *Sockets) {
+		ss.OnAsync(EventTypeMessage, func(_ *Socket, v any) {
+			<-gate
+			m := v.(message.Message)
+			received <- fmt.Sprintf("%s:%s", messagePath(m), m.Body())
+		})
+		_ = ss.Service().Register(func(c *Context) any {
+			return []byte("pong")
+		}, "/ping")
+	})
+	sock, replies := testClient(t, address)
+	for i := 0; i < total; i++ {
+		_ = sock.Send(message.FlagNoreply, int32(i+1), fmt.Sprintf("/unknown/%d", i), []byte(fmt.Sprintf("body%d", i)))
+	}
+	// 收到回复时前面的消息均已处理完毕并回收
+	_ = sock.Send(0, total+1, "/ping", nil)
+	testReceive(t, replies)
+	close(gate)
+	for i := 0; i < total; i++ {
+		want := fmt.Sprintf("/unknown/%d:body%d", i, i)
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("async message %q, want %q", got, want)
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("async message %q not received", want)
+		}
+	}
+}
+
+func TestSubscriptionOff(t *testing.T) {
+	ss := New()
+	var sync, async int32
+	done := make(chan struct{}, 1)
+	s1 := ss.On(EventTypeError, func(*Socket, any) {
+		atomic.AddInt32(&sync, 1)
+	})
+	s2 := ss.OnAsync(EventTypeError, func(*Socket, any) {
+		atomic.AddInt32(&async, 1)
+		done <- struct{}{}
+	})
+	ss.Emit(EventTypeError, nil, "first")
+	<-done
+	s1.Off()
+	s2.Off()
+	s2.Off()
+	ss.Emit(EventTypeError, nil, "second")
+	if n := atomic.LoadInt32(&sync); n != 1 {
+		t.Fatalf("sync listener called %d times", n)
+	}
+	if n := atomic.LoadInt32(&async); n != 1 {
+		t.Fatalf("async listener called %d times", n)
+	}
+}
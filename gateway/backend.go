@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"github.com/hwcer/cosgo/session"
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// identityKey 请求级数据中 Identity 的键
+type identityKey struct{}
+
+// Identity 网关转发的客户端身份。
+type Identity struct {
+	Socket  uint64         `json:"socket"`            // 客户端在网关上的 Socket ID
+	UUID    string         `json:"uuid,omitempty"`    // 客户端会话的 UUID，未认证时为空
+	Address string         `json:"address,omitempty"` // 客户端地址
+	Values  map[string]any `json:"values,omitempty"`  // Options.Values 指定的会话数据
+	link    *cosnet.Socket // 网关到本服务的连接
+}
+
+// Push 通过网关推送消息给客户端，可以保存 Identity 在请求之外使用，网关连接断开后返回错误。
+// 参数:
+//   - flag: 消息标志
+//   - path: 消息路径或协议号
+//   - data: 消息数据
+//
+// 返回值: 错误信息
+func (id *Identity) Push(flag message.Flag, path any, data any) error {
+	m := message.Require(id.link.Codec())
+	defer message.Release(m)
+	if err := m.Marshal(id.link.Codec().DefaultMagic(), flag, 0, path, data); err != nil {
+		return err
+	}
+	body, err := pushFrame(id.link.Codec(), id.Socket, m)
+	if err != nil {
+		return err
+	}
+	return id.link.Send(message.FlagNoreply, 0, PushPath, body)
+}
+
+// FromContext 获取网关转发的客户端身份。
+// 参数 c: 请求上下文。
+// 返回值: 不是网关转发的请求时返回 nil。
+func FromContext(c *cosnet.Context) *Identity {
+	id, _ := c.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// Serve 在后端服务上注册网关转发路由（初始化时使用）。
+// 网关转发的消息按正常流程路由处理，Context.Data 为客户端的会话数据（未认证时为 nil），回复经网关按原序号返回客户端。
+// 转发路由信任网关提供的身份，后端服务不应对客户端开放监听地址。
+// 参数 ss: 后端服务的 Sockets 实例。
+// 返回值: 错误信息
+func Serve(ss *cosnet.Sockets) error {
+	if err := ss.Service("").Register(serve, ForwardPath); err != nil {
+		return err
+	}
+	ss.Handler().Public(ForwardPath)
+	return nil
+}
+
+func serve(c *cosnet.Context) any {
+	id, m, err := parseForward(c.Socket.Codec(), c.Message.Body())
+	if err != nil {
+		logger.Debug("gateway serve error:%v", err)
+		return nil
+	}
+	defer message.Release(m)
+	id.link = c.Socket
+	var data *session.Data
+	if id.UUID != "" {
+		data = session.NewData(id.UUID, id.Values)
+	}
+	c.Socket.Serve(m, data, map[any]any{identityKey{}: id})
+	return nil
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/hwcer/cosgo/binder"
+	"github.com/hwcer/cosnet/message"
+)
+
+// 网关和后端服务之间使用的路由。
+const (
+	ForwardPath = "/gateway/forward" // 网关转发客户端消息，包体为 Identity + 客户端消息
+	PushPath    = "/gateway/push"    // 后端推送消息给客户端，包体为客户端 Socket ID + 消息
+)
+
+// ErrFrame 网关数据包格式错误。
+var ErrFrame = errors.New("gateway frame error")
+
+// route 取出消息的路径（path 模式，包含查询参数）或协议号（code 模式）
+func route(m message.Message) (any, error) {
+	magic := m.Magic()
+	if magic == nil {
+		return nil, ErrFrame
+	}
+	if magic.Type != message.MagicTypePath {
+		return m.Code(), nil
+	}
+	p, q, err := m.Path()
+	if err != nil {
+		return nil, err
+	}
+	if q != "" {
+		p = p + "?" + q
+	}
+	return p, nil
+}
+
+// encode 使用新的序号重新编码消息，写入 buf
+func encode(buf *bytes.Buffer, codec *message.Codec, m message.Message, index int32) error {
+	path, err := route(m)
+	if err != nil {
+		return err
+	}
+	inner := message.Require(codec)
+	defer message.Release(inner)
+	if err = inner.Marshal(m.Magic().Key, m.Flag(), index, path, m.Body()); err != nil {
+		return err
+	}
+	_, err = inner.Bytes(buf, true)
+	return err
+}
+
+// decode 解析消息，b 会被复制
+func decode(codec *message.Codec, b []byte) (message.Message, error) {
+	m := message.Require(codec)
+	if err := m.Reset(bytes.Clone(b)); err != nil {
+		message.Release(m)
+		return nil, err
+	}
+	return m, nil
+}
+
+// forwardFrame 编码转发包：Identity 长度(4) + Identity(JSON) + 消息
+func forwardFrame(codec *message.Codec, id *Identity, m message.Message, index int32) ([]byte, error) {
+	head, err := binder.Json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(make([]byte, 4, 4+len(head)+int(m.Size())+32))
+	binary.BigEndian.PutUint32(buf.Bytes(), uint32(len(head)))
+	buf.Write(head)
+	if err = encode(buf, codec, m, index); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseForward 解析转发包
+func parseForward(codec *message.Codec, b []byte) (*Identity, message.Message, error) {
+	if len(b) < 4 {
+		return nil, nil, ErrFrame
+	}
+	n := 4 + int(binary.BigEndian.Uint32(b))
+	if n < 4 || len(b) < n {
+		return nil, nil, ErrFrame
+	}
+	id := &Identity{}
+	if err := binder.Json.Unmarshal(b[4:n], id); err != nil {
+		return nil, nil, err
+	}
+	m, err := decode(codec, b[n:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, m, nil
+}
+
+// pushFrame 编码推送包：客户端 Socket ID(8) + 消息
+func pushFrame(codec *message.Codec, socket uint64, m message.Message) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 8, 8+int(m.Size())+32))
+	binary.BigEndian.PutUint64(buf.Bytes(), socket)
+	if _, err := m.Bytes(buf, true); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parsePush 解析推送包
+func parsePush(codec *message.Codec, b []byte) (uint64, message.Message, error) {
+	if len(b) < 8 {
+		return 0, nil, ErrFrame
+	}
+	m, err := decode(codec, b[8:])
+	if err != nil {
+		return 0, nil, err
+	}
+	return binary.BigEndian.Uint64(b), m, nil
+}
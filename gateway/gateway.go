@@ -0,0 +1,317 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hwcer/cosgo/scc"
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// ErrBackendUnavailable 后端服务不可用。
+var ErrBackendUnavailable = errors.New("gateway backend unavailable")
+
+// Options 网关配置。
+type Options struct {
+	Timeout time.Duration       // 等待后端回复的最长时间，超时后丢弃回复，默认 30 秒
+	Values  []string            // 随消息转发给后端的会话数据字段，默认只转发 UUID
+	Dialer  *cosnet.DialOptions // 连接后端服务的选项
+}
+
+// Route 转发规则，Prefix 和 Code 范围二选一。
+type Route struct {
+	Prefix  string // path 模式的路径前缀，例如 "/game/"
+	CodeMin int32  // code 模式的协议号范围（包含）
+	CodeMax int32
+	Address string // 后端服务地址，格式与 Sockets.Connect 相同
+}
+
+// match 消息是否符合转发规则
+func (r *Route) match(path string, code int32, isCode bool) bool {
+	if r.Prefix != "" {
+		return !isCode && strings.HasPrefix(path, r.Prefix)
+	}
+	return isCode && code >= r.CodeMin && code <= r.CodeMax
+}
+
+// New 创建网关，调用 Start 后开始转发。
+// 参数:
+//   - ss: 接受客户端连接的 Sockets 实例，未匹配转发规则的消息仍由其路由处理（例如在网关登录）
+//   - opts: 网关配置，可选
+func New(ss *cosnet.Sockets, opts ...Options) *Gateway {
+	g := &Gateway{
+		sockets:  ss,
+		backends: map[string]*backend{},
+		pending:  map[int32]*pending{},
+		Link:     cosnet.New(),
+	}
+	if len(opts) > 0 {
+		g.Options = opts[0]
+	}
+	if g.Options.Timeout <= 0 {
+		g.Options.Timeout = 30 * time.Second
+	}
+	return g
+}
+
+// Gateway 网关，按路径前缀或协议号范围把客户端消息转发到后端服务，并将回复按原序号返回客户端。
+type Gateway struct {
+	index    int32
+	mutex    sync.Mutex
+	routes   []*Route
+	sockets  *cosnet.Sockets
+	backends map[string]*backend
+	pending  map[int32]*pending
+	cancel   context.CancelFunc
+	Link     *cosnet.Sockets // 到后端服务的连接管理器，可以在 Start 之前修改其配置
+	Options  Options
+}
+
+// backend 到后端服务的连接
+type backend struct {
+	mutex sync.Mutex
+	sock  *cosnet.Socket
+}
+
+// pending 等待后端回复的请求
+type pending struct {
+	socket  uint64 // 客户端 Socket ID
+	index   int32  // 客户端请求的原始序号
+	expires int64  // 过期时间，UnixNano
+}
+
+// Prefix 添加按路径前缀转发的规则，多个规则匹配时使用最长的前缀（初始化时使用）。
+// 参数:
+//   - prefix: 路径前缀
+//   - address: 后端服务地址
+func (g *Gateway) Prefix(prefix string, address string) {
+	g.routes = append(g.routes, &Route{Prefix: prefix, Address: address})
+	sort.SliceStable(g.routes, func(i, j int) bool {
+		return len(g.routes[i].Prefix) > len(g.routes[j].Prefix)
+	})
+}
+
+// Codes 添加按协议号范围转发的规则，范围重叠时使用先添加的规则（初始化时使用）。
+// 参数:
+//   - min: 最小协议号（包含）
+//   - max: 最大协议号（包含）
+//   - address: 后端服务地址
+func (g *Gateway) Codes(min, max int32, address string) {
+	g.routes = append(g.routes, &Route{CodeMin: min, CodeMax: max, Address: address})
+}
+
+// Start 注册拦截器和推送路由，开始转发。
+// 返回值: 错误信息
+func (g *Gateway) Start() error {
+	if err := g.Link.Service("").Register(g.push, PushPath); err != nil {
+		return err
+	}
+	g.Link.Intercept(g.reply)
+	if err := g.Link.Start(); err != nil {
+		return err
+	}
+	g.sockets.Intercept(g.forward)
+	ctx, cancel := scc.WithCancel()
+	g.cancel = cancel
+	scc.SGO(func(_ context.Context) {
+		g.daemon(ctx)
+	})
+	return nil
+}
+
+// Close 停止清理并断开所有后端连接，拦截器无法移除，之后匹配的消息会回复 ErrorCodeBadGateway。
+func (g *Gateway) Close() {
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.mutex.Lock()
+	backends := g.backends
+	g.backends = map[string]*backend{}
+	g.mutex.Unlock()
+	for _, b := range backends {
+		if b.sock != nil {
+			b.sock.Close()
+		}
+	}
+}
+
+// match 查找消息的转发规则
+func (g *Gateway) match(m message.Message) *Route {
+	magic := m.Magic()
+	if magic == nil {
+		return nil
+	}
+	var path string
+	var code int32
+	isCode := magic.Type != message.MagicTypePath
+	if isCode {
+		code = m.Code()
+	} else {
+		var err error
+		if path, _, err = m.Path(); err != nil {
+			return nil
+		}
+	}
+	for _, r := range g.routes {
+		if r.match(path, code, isCode) {
+			return r
+		}
+	}
+	return nil
+}
+
+// forward 客户端消息拦截器，匹配转发规则的消息转发到后端服务
+func (g *Gateway) forward(sock *cosnet.Socket, msg message.Message) bool {
+	flag := msg.Flag()
+	if sock.Type() != listener.SocketTypeServer || flag.Has(message.FlagConfirm) || flag.Has(message.FlagHeartbeat) {
+		return false
+	}
+	r := g.match(msg)
+	if r == nil {
+		return false
+	}
+	if err := g.send(r, sock, msg); err != nil {
+		logger.Debug("gateway forward %v error:%v", r.Address, err)
+		_ = sock.ReplyError(msg, cosnet.NewError(cosnet.ErrorCodeBadGateway, err))
+	}
+	return true
+}
+
+func (g *Gateway) send(r *Route, sock *cosnet.Socket, msg message.Message) error {
+	link, err := g.backend(r.Address)
+	if err != nil {
+		return err
+	}
+	id := &Identity{Socket: sock.Id()}
+	if conn := sock.RemoteAddr(); conn != nil {
+		id.Address = conn.String()
+	}
+	if data := sock.Data(); data != nil {
+		id.UUID = data.UUID()
+		for _, k := range g.Options.Values {
+			if v := data.Get(k); v != nil {
+				if id.Values == nil {
+					id.Values = map[string]any{}
+				}
+				id.Values[k] = v
+			}
+		}
+	}
+	index := atomic.AddInt32(&g.index, 1)
+	body, err := forwardFrame(link.Codec(), id, msg, index)
+	if err != nil {
+		return err
+	}
+	noreply := msg.Flag().Has(message.FlagNoreply)
+	if !noreply {
+		g.mutex.Lock()
+		g.pending[index] = &pending{socket: sock.Id(), index: msg.Index(), expires: time.Now().Add(g.Options.Timeout).UnixNano()}
+		g.mutex.Unlock()
+	}
+	if err = link.Send(message.FlagNoreply, 0, ForwardPath, body); err != nil && !noreply {
+		g.mutex.Lock()
+		delete(g.pending, index)
+		g.mutex.Unlock()
+	}
+	return err
+}
+
+// backend 获取到后端服务的连接，不存在时创建
+func (g *Gateway) backend(address string) (*cosnet.Socket, error) {
+	g.mutex.Lock()
+	b := g.backends[address]
+	if b == nil {
+		b = &backend{}
+		g.backends[address] = b
+	}
+	g.mutex.Unlock()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.sock != nil && b.sock.Status() != cosnet.SocketStatusReleased {
+		if !b.sock.IsReady() {
+			return nil, ErrBackendUnavailable
+		}
+		return b.sock, nil
+	}
+	var err error
+	if b.sock, err = g.Link.Connect(address, g.Options.Dialer); err != nil {
+		return nil, err
+	}
+	return b.sock, nil
+}
+
+// reply 后端回复拦截器，按序号找到客户端并使用原始序号返回
+func (g *Gateway) reply(_ *cosnet.Socket, msg message.Message) bool {
+	if !msg.Flag().Has(message.FlagConfirm) {
+		return false
+	}
+	g.mutex.Lock()
+	p := g.pending[msg.Index()]
+	delete(g.pending, msg.Index())
+	g.mutex.Unlock()
+	if p == nil {
+		return true
+	}
+	sock := g.sockets.Get(p.socket)
+	if sock == nil {
+		return true
+	}
+	path, err := route(msg)
+	if err == nil {
+		err = sock.SendWithMagic(msg.Magic().Key, msg.Flag(), p.index, path, msg.Body())
+	}
+	if err != nil {
+		logger.Debug("gateway reply to %v error:%v", p.socket, err)
+	}
+	return true
+}
+
+// push 后端推送消息给客户端
+func (g *Gateway) push(c *cosnet.Context) any {
+	id, m, err := parsePush(c.Socket.Codec(), c.Message.Body())
+	if err != nil {
+		logger.Debug("gateway push error:%v", err)
+		return nil
+	}
+	sock := g.sockets.Get(id)
+	if sock == nil {
+		message.Release(m)
+		return nil
+	}
+	m.SetCodec(sock.Codec())
+	if err = sock.Write(m); err != nil {
+		message.Release(m)
+		logger.Debug("gateway push to %v error:%v", id, err)
+	}
+	return nil
+}
+
+// daemon 定时清理超时未回复的请求
+func (g *Gateway) daemon(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now().UnixNano()
+			g.mutex.Lock()
+			for k, p := range g.pending {
+				if p.expires <= now {
+					delete(g.pending, k)
+				}
+			}
+			g.mutex.Unlock()
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosgo/session"
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/message"
+)
+
+type echo struct {
+	Uid  string `json:"uid"`
+	Text string `json:"text"`
+}
+
+func TestGateway(t *testing.T) {
+	backend := cosnet.New()
+	backend.Options.AuthenticationRequired = true
+	if err := Serve(backend); err != nil {
+		t.Fatal(err)
+	}
+	err := cosnet.Route(backend, "/game/echo", func(c *cosnet.Context, req *echo) (*echo, error) {
+		id := FromContext(c)
+		if id == nil {
+			return nil, fmt.Errorf("identity missing")
+		}
+		if err := id.Push(0, "/game/notice", []byte("pushed")); err != nil {
+			return nil, err
+		}
+		return &echo{Uid: c.Data().UUID(), Text: req.Text}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = backend.Listen("tcp://127.0.0.1:19301"); err != nil {
+		t.Fatal(err)
+	}
+	if err = backend.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	front := cosnet.New()
+	err = cosnet.Route(front, "/login", func(c *cosnet.Context, req *echo) (*echo, error) {
+		c.Socket.Authentication(session.NewData(req.Uid, nil))
+		return req, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := New(front)
+	g.Prefix("/game/", "tcp://127.0.0.1:19301")
+	if err = g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	if _, err = front.Listen("tcp://127.0.0.1:19302"); err != nil {
+		t.Fatal(err)
+	}
+	if err = front.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan string, 8)
+	cl := cosnet.New()
+	cl.On(cosnet.EventTypeMessage, func(_ *cosnet.Socket, v any) {
+		m := v.(message.Message)
+		path, _, _ := m.Path()
+		got <- fmt.Sprintf("%d %s:%s", m.Index(), path, strings.TrimSpace(string(m.Body())))
+	})
+	sock, err := cl.Connect("tcp://127.0.0.1:19302")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	expect := func(want ...string) {
+		t.Helper()
+		for range want {
+			select {
+			case v := <-got:
+				found := false
+				for _, w := range want {
+					found = found || v == w
+				}
+				if !found {
+					t.Fatalf("got %q, want one of %q", v, want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timeout waiting %q", want)
+			}
+		}
+	}
+
+	// 未登录时后端拒绝
+	if err = sock.Send(0, 3, "/game/echo", &echo{Text: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(`3 /game/echo:{"code":401,"message":"authentication required"}`)
+
+	if err = sock.Send(0, 1, "/login", &echo{Uid: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(`1 /login:{"uid":"u1","text":""}`)
+
+	if err = sock.Send(0, 7, "/game/echo", &echo{Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(`0 /game/notice:pushed`, `7 /game/echo:{"uid":"u1","text":"hi"}`)
+}
@@ -0,0 +1,34 @@
+package cosnet
+
+// Join 加入广播分组，客户端模式断线重连后依然保留，Socket 销毁时自动退出所有分组。
+// 参数 groups: 分组名称。
+func (sock *Socket) Join(groups ...string) {
+	for _, g := range groups {
+		sock.groups.Store(g, struct{}{})
+	}
+}
+
+// Leave 退出广播分组。
+// 参数 groups: 分组名称。
+func (sock *Socket) Leave(groups ...string) {
+	for _, g := range groups {
+		sock.groups.Delete(g)
+	}
+}
+
+// InGroup 是否已加入广播分组。
+// 参数 group: 分组名称。
+// 返回值: 已加入时返回 true。
+func (sock *Socket) InGroup(group string) bool {
+	_, ok := sock.groups.Load(group)
+	return ok
+}
+
+// Groups 已加入的所有广播分组。
+func (sock *Socket) Groups() (r []string) {
+	sock.groups.Range(func(k, _ any) bool {
+		r = append(r, k.(string))
+		return true
+	})
+	return
+}
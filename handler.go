@@ -2,8 +2,10 @@ package cosnet
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/hwcer/cosgo/registry"
+	"github.com/hwcer/cosgo/values"
 	"github.com/hwcer/cosnet/message"
 	"github.com/hwcer/logger"
 )
@@ -41,11 +43,36 @@ type HandlerCaller func(node *registry.Node, c *Context) any
 //   - error: 错误信息
 type HandlerSerialize func(c *Context, reply any) ([]byte, error)
 
+// HandlerFunc 定义消息处理函数类型。
+// 参数 c: 上下文。
+// 返回值: 响应数据。
+type HandlerFunc func(c *Context) any
+
+// HandlerMiddleware 定义处理器中间件类型，包裹下一个处理函数并返回新的处理函数。
+// 中间件不调用 next 而直接返回响应数据时，后续中间件和业务处理函数都不会执行（短路）。
+type HandlerMiddleware func(next HandlerFunc) HandlerFunc
+
+// Interceptor 消息拦截器，在路由之前调用，返回 true 表示消息已经处理（例如已转发到其它服务），不再路由。
+// 拦截器返回后消息会被回收，需要异步使用时应复制。
+type Interceptor func(sock *Socket, msg message.Message) bool
+
+// 服务级身份认证要求。
+const (
+	handlerAuthInherit  int8 = iota // 继承 Config.AuthenticationRequired
+	handlerAuthPublic               // 公开，无需身份认证
+	handlerAuthRequired             // 需要身份认证
+)
+
 // Handler 消息处理器，用于处理消息和生成响应。
 type Handler struct {
-	filter    HandlerFilter    // 处理器过滤器
-	caller    HandlerCaller    // 处理器调用函数
-	serialize HandlerSerialize // 消息序列化函数，仅针对确认包
+	auth       int8                     // 服务级身份认证要求
+	routes     map[string]bool          // 路由级身份认证要求，优先于服务级
+	expire     time.Duration            // 服务级处理超时时间
+	expires    map[string]time.Duration // 路由级处理超时时间，优先于服务级
+	filter     HandlerFilter            // 处理器过滤器
+	caller     HandlerCaller            // 处理器调用函数
+	serialize  HandlerSerialize         // 消息序列化函数，仅针对确认包
+	middleware []HandlerMiddleware      // 服务级中间件
 }
 
 // SetCaller 设置处理器调用函数。
@@ -66,6 +93,87 @@ func (this *Handler) SetSerialize(serialize func(c *Context, reply any) ([]byte,
 	this.serialize = serialize
 }
 
+// Use 添加服务级中间件，仅作用于当前服务。
+// 按添加顺序由外到内执行，全部位于 Sockets.Use 注册的全局中间件之内。
+// 参数 middleware: 中间件列表。
+func (this *Handler) Use(middleware ...HandlerMiddleware) {
+	this.middleware = append(this.middleware, middleware...)
+}
+
+// Public 声明公开路由，未认证的 Socket 也可以调用（初始化时使用）。
+// 参数 routes: 完整路由路径，为空时将整个服务声明为公开。
+func (this *Handler) Public(routes ...string) {
+	this.setAuthentication(false, routes)
+}
+
+// Protected 声明需要身份认证的路由，未认证的 Socket 调用时会被拒绝（初始化时使用）。
+// 参数 routes: 完整路由路径，为空时将整个服务声明为需要认证。
+func (this *Handler) Protected(routes ...string) {
+	this.setAuthentication(true, routes)
+}
+
+func (this *Handler) setAuthentication(required bool, routes []string) {
+	if len(routes) == 0 {
+		if required {
+			this.auth = handlerAuthRequired
+		} else {
+			this.auth = handlerAuthPublic
+		}
+		return
+	}
+	if this.routes == nil {
+		this.routes = make(map[string]bool)
+	}
+	for _, r := range routes {
+		this.routes[registry.Route(r)] = required
+	}
+}
+
+// authentication 判断路由是否需要身份认证。
+// 参数:
+//   - node: 注册的节点
+//   - def: 未声明时的默认值
+//
+// 返回值: 是否需要身份认证。
+func (this *Handler) authentication(node *registry.Node, def bool) bool {
+	if v, ok := this.routes[node.Name()]; ok {
+		return v
+	}
+	switch this.auth {
+	case handlerAuthPublic:
+		return false
+	case handlerAuthRequired:
+		return true
+	default:
+		return def
+	}
+}
+
+// SetTimeout 设置处理超时时间，超时后 Context 被取消（初始化时使用）。
+// 参数:
+//   - d: 超时时间，0 表示不限制
+//   - routes: 完整路由路径，为空时设置整个服务
+func (this *Handler) SetTimeout(d time.Duration, routes ...string) {
+	if len(routes) == 0 {
+		this.expire = d
+		return
+	}
+	if this.expires == nil {
+		this.expires = make(map[string]time.Duration)
+	}
+	for _, r := range routes {
+		this.expires[registry.Route(r)] = d
+	}
+}
+
+// timeout 获取路由的处理超时时间。
+func (this *Handler) timeout(node *registry.Node) time.Duration {
+	if d, ok := this.expires[node.Name()]; ok {
+		return d
+	}
+	return this.expire
+}
+
 // Filter 过滤处理器。
 // 参数 node: 注册的节点。
 // 返回值: 是否通过过滤。
@@ -91,6 +199,29 @@ func (this *Handler) Filter(node *registry.Node) bool {
 	}
 }
 
+// serve 使用中间件包裹 handle 并执行。
+// 参数:
+//   - node: 注册的节点
+//   - c: 上下文
+//   - global: 全局中间件，位于服务级中间件之外
+//
+// 返回值: 响应数据。
+func (this *Handler) serve(node *registry.Node, c *Context, global []HandlerMiddleware) any {
+	if len(global) == 0 && len(this.middleware) == 0 {
+		return this.handle(node, c)
+	}
+	next := HandlerFunc(func(c *Context) any {
+		return this.handle(node, c)
+	})
+	for i := len(this.middleware) - 1; i >= 0; i-- {
+		next = this.middleware[i](next)
+	}
+	for i := len(global) - 1; i >= 0; i-- {
+		next = global[i](next)
+	}
+	return next(c)
+}
+
 // handle 处理消息。
 // 参数:
 //   - node: 注册的节点
@@ -132,6 +263,16 @@ func (this *Handler) reply(c *Context, reply any) (err error) {
 	replyConfirm := c.Message.Confirm()
 	replyMagic := c.Message.Magic()
 
+	switch v := reply.(type) {
+	case *values.Message:
+		// values.Parse 生成的正常响应同样实现了 error 接口，仅错误码非 0 时作为错误回复
+		if v.Code != 0 {
+			return c.Socket.replyError(c.Message, v)
+		}
+	case error:
+		return c.Socket.replyError(c.Message, v)
+	}
+
 	switch v := reply.(type) {
 	case []byte:
 		err = c.Socket.SendWithMagic(replyMagic.Key, replyFlag, replyIndex, replyConfirm, v)
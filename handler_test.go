@@ -0,0 +1,111 @@
+package cosnet
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hwcer/cosgo/session"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var mutex sync.Mutex
+	var order []string
+	trace := func(name string) HandlerMiddleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) any {
+				mutex.Lock()
+				order = append(order, name)
+				mutex.Unlock()
+				return next(c)
+			}
+		}
+	}
+	handler := func(c *Context) any {
+		mutex.Lock()
+		order = append(order, "handler")
+		mutex.Unlock()
+		return []byte("ok")
+	}
+	_, address := testServer(t, func(ss *Sockets) {
+		ss.Use(trace("global1"), trace("global2"))
+		if err := ss.Service().Register(handler, "/order"); err != nil {
+			t.Fatal(err)
+		}
+		if err := ss.Service().Register(handler, "/short"); err != nil {
+			t.Fatal(err)
+		}
+		ss.Handler().Use(trace("service"))
+		ss.Use(func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) any {
+				if p, _, _ := c.Path(); p == "/short" {
+					mutex.Lock()
+					order = append(order, "short")
+					mutex.Unlock()
+					return []byte("short")
+				}
+				return next(c)
+			}
+		})
+	})
+	sock, replies := testClient(t, address)
+	for _, tc := range []struct{ path, reply, order string }{
+		{"/order", "ok", "global1,global2,service,handler"},
+		{"/short", "short", "global1,global2,short"}, // 中断后内层中间件和处理函数都不执行
+	} {
+		mutex.Lock()
+		order = nil
+		mutex.Unlock()
+		if err := sock.Send(0, 1, tc.path, nil); err != nil {
+			t.Fatal(err)
+		}
+		if r := testReceive(t, replies); string(r.body) != tc.reply {
+			t.Fatalf("%v reply %q", tc.path, r.body)
+		}
+		mutex.Lock()
+		got := strings.Join(order, ",")
+		mutex.Unlock()
+		if got != tc.order {
+			t.Fatalf("%v middleware order %v", tc.path, got)
+		}
+	}
+}
+
+func TestAuthenticationRequired(t *testing.T) {
+	var unauthorized int32
+	_, address := testServer(t, func(ss *Sockets) {
+		ss.Options.AuthenticationRequired = true
+		ss.On(EventTypeUnauthorized, func(*Socket, any) {
+			atomic.AddInt32(&unauthorized, 1)
+		})
+		service := ss.Service()
+		_ = service.Register(func(c *Context) any {
+			c.Socket.Authentication(session.NewData("u1", nil))
+			return []byte("login")
+		}, "/login")
+		_ = service.Register(func(c *Context) any {
+			return []byte("private")
+		}, "/private")
+		ss.Handler().Public("/login")
+	})
+	sock, replies := testClient(t, address)
+
+	_ = sock.Send(0, 1, "/private", nil)
+	r := testReceive(t, replies)
+	if e := testError(t, r); e == nil || e.Code != ErrorCodeUnauthorized {
+		t.Fatalf("unauthenticated reply %+v", r)
+	}
+	if n := atomic.LoadInt32(&unauthorized); n != 1 {
+		t.Fatalf("unauthorized events %d", n)
+	}
+
+	_ = sock.Send(0, 2, "/login", nil)
+	if r = testReceive(t, replies); string(r.body) != "login" {
+		t.Fatalf("login reply %+v", r)
+	}
+	_ = sock.Send(0, 3, "/private", nil)
+	if r = testReceive(t, replies); testError(t, r) != nil || string(r.body) != "private" {
+		t.Fatalf("authenticated reply %+v", r)
+	}
+}
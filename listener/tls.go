@@ -0,0 +1,186 @@
+package listener
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLSConn 使用 TLS 的连接实现该接口，用于获取握手结果和对端证书。
+type TLSConn interface {
+	// TLSConnectionState 返回 TLS 连接状态，没有使用 TLS 时第二个返回值为 false。
+	TLSConnectionState() (tls.ConnectionState, bool)
+}
+
+// NewCertificates 创建证书管理器。
+func NewCertificates() *Certificates {
+	return &Certificates{names: map[string]*tls.Certificate{}}
+}
+
+// Certificates 证书管理器，按 SNI 选择证书，支持不重启重新加载证书文件。
+// 将 GetCertificate 设置到 tls.Config 或者直接使用 TLSConfig。
+type Certificates struct {
+	mutex   sync.RWMutex
+	files   []*certificateFile
+	names   map[string]*tls.Certificate // 域名（支持 *.example.com）=> 证书
+	primary *tls.Certificate            // 没有匹配的域名时使用的证书，为第一个加载的证书
+}
+
+type certificateFile struct {
+	cert    string
+	key     string
+	names   []string
+	modTime time.Time
+}
+
+// Load 加载证书文件，第一个加载的证书为默认证书。
+// 参数:
+//   - certFile: 证书文件（PEM）
+//   - keyFile: 私钥文件（PEM）
+//   - names: 使用该证书的域名，为空时使用证书中的 CommonName 和 DNSNames
+//
+// 返回值: 错误信息
+func (c *Certificates) Load(certFile, keyFile string, names ...string) error {
+	f := &certificateFile{cert: certFile, key: keyFile, names: names}
+	cert, modTime, err := f.load()
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.files = append(c.files, f)
+	c.set(f, cert, modTime)
+	return nil
+}
+
+// Reload 重新加载所有证书文件，任意一个失败时保持原有证书不变。
+// 返回值: 错误信息
+func (c *Certificates) Reload() error {
+	c.mutex.RLock()
+	files := append([]*certificateFile(nil), c.files...)
+	c.mutex.RUnlock()
+	certs := make([]*tls.Certificate, len(files))
+	times := make([]time.Time, len(files))
+	for i, f := range files {
+		cert, modTime, err := f.load()
+		if err != nil {
+			return err
+		}
+		certs[i], times[i] = cert, modTime
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.names = map[string]*tls.Certificate{}
+	c.primary = nil
+	for i, f := range files {
+		c.set(f, certs[i], times[i])
+	}
+	return nil
+}
+
+// Watch 定时检查证书文件的修改时间，有变化时重新加载。
+// 参数:
+//   - interval: 检查间隔
+//   - onError: 重新加载失败时的回调，可以为 nil
+//
+// 返回值: 停止检查的函数。
+func (c *Certificates) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if c.changed() {
+					if err := c.Reload(); err != nil && onError != nil {
+						onError(err)
+					}
+				}
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// changed 证书文件是否有修改
+func (c *Certificates) changed() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, f := range c.files {
+		for _, name := range []string{f.cert, f.key} {
+			if info, err := os.Stat(name); err == nil && info.ModTime().After(f.modTime) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetCertificate 按 SNI 选择证书，实现 tls.Config.GetCertificate。
+func (c *Certificates) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if cert, ok := c.names[name]; ok {
+		return cert, nil
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if cert, ok := c.names["*"+name[i:]]; ok {
+			return cert, nil
+		}
+	}
+	if c.primary == nil {
+		return nil, fmt.Errorf("no certificate for %q", hello.ServerName)
+	}
+	return c.primary, nil
+}
+
+// TLSConfig 返回使用该证书管理器的 TLS 配置，可以继续设置 ClientAuth、ClientCAs 等字段开启双向认证。
+func (c *Certificates) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: c.GetCertificate, MinVersion: tls.VersionTLS12}
+}
+
+func (c *Certificates) set(f *certificateFile, cert *tls.Certificate, modTime time.Time) {
+	f.modTime = modTime
+	if c.primary == nil {
+		c.primary = cert
+	}
+	names := f.names
+	if len(names) == 0 && cert.Leaf != nil {
+		names = cert.Leaf.DNSNames
+		if cn := cert.Leaf.Subject.CommonName; cn != "" {
+			names = append([]string{cn}, names...)
+		}
+	}
+	for _, name := range names {
+		c.names[strings.ToLower(name)] = cert
+	}
+}
+
+// load 读取证书文件，返回证书和文件的最后修改时间
+func (f *certificateFile) load() (*tls.Certificate, time.Time, error) {
+	var modTime time.Time
+	for _, name := range []string{f.cert, f.key} {
+		if info, err := os.Stat(name); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(f.cert, f.key)
+	if err != nil {
+		return nil, modTime, err
+	}
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, modTime, err
+		}
+	}
+	return &cert, modTime, nil
+}
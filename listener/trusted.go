@@ -0,0 +1,77 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrTrustedRequired 开启了真实客户端地址解析，但没有设置受信任的上游地址。
+var ErrTrustedRequired = errors.New("trusted upstream address list is required")
+
+// Trusted 受信任的上游地址列表（负载均衡、反向代理），用于判断是否接受其提供的真实客户端地址。
+// 列表为空时不信任任何地址，确实需要信任所有地址时显式设置 "0.0.0.0/0" 和 "::/0"。
+type Trusted []*net.IPNet
+
+// ParseTrusted 解析受信任的上游地址。
+// 参数 list: IP 或 CIDR，例如 "10.0.0.1"、"10.0.0.0/8"。
+// 返回值: 地址格式错误时返回错误。
+func ParseTrusted(list []string) (Trusted, error) {
+	r := make(Trusted, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted address invalid:%v", s)
+			}
+			bits := 128
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			r = append(r, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, n)
+	}
+	return r, nil
+}
+
+// Contains 是否为受信任的上游地址，列表为空时不信任任何地址。
+// 参数 addr: 上游地址。
+func (t Trusted) Contains(addr net.Addr) bool {
+	var ip net.IP
+	switch v := addr.(type) {
+	case *net.TCPAddr:
+		ip = v.IP
+	case *net.UDPAddr:
+		ip = v.IP
+	case nil:
+		return false
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			host = addr.String()
+		}
+		ip = net.ParseIP(host)
+	}
+	return t.ContainsIP(ip)
+}
+
+// ContainsIP 是否为受信任的上游 IP，列表为空时不信任任何地址。
+func (t Trusted) ContainsIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range t {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package listener
+
+// Credentials Unix domain socket 对端进程的身份，由内核在连接时记录，不能伪造。
+type Credentials struct {
+	PID int32  // 对端进程 ID
+	UID uint32 // 对端进程的用户 ID
+	GID uint32 // 对端进程的用户组 ID
+}
+
+// CredentialsConn 可以获取对端进程身份的连接实现该接口。
+type CredentialsConn interface {
+	// PeerCredentials 返回对端进程的身份，没有读取或者不支持时第二个返回值为 false。
+	PeerCredentials() (Credentials, bool)
+}
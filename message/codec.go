@@ -0,0 +1,62 @@
+package message
+
+// Codec 消息编解码配置，可以按 Sockets 实例或监听器分别设置，零值字段使用包级全局配置。
+// 消息通过 Require(codec) 或 SetCodec 绑定 Codec，未绑定时使用全局配置。
+type Codec struct {
+	Magic            byte      // 默认魔数，0 使用 Options.Magic
+	Magics           magics    // 可用的魔数，nil 使用 Magics，可以使用 Magics.Clone() 复制后再注册
+	Transform        transform // code 模式的路径转换，nil 使用 Transform
+	MaxDataSize      int32     // 单包最大长度，0 使用 Options.MaxDataSize
+	AutoCompressSize int32     // 自动压缩的阈值，0 使用 Options.AutoCompressSize，小于 0 表示不压缩
+	S2CConfirm       string    // 确认包协议，空使用 Options.S2CConfirm
+}
+
+// DefaultMagic 默认魔数。
+func (c *Codec) DefaultMagic() byte {
+	if c != nil && c.Magic != 0 {
+		return c.Magic
+	}
+	return Options.Magic
+}
+
+// HasMagic 是否为可用的魔数。
+func (c *Codec) HasMagic(key byte) bool {
+	return c.magics().Has(key)
+}
+
+func (c *Codec) magics() magics {
+	if c != nil && c.Magics != nil {
+		return c.Magics
+	}
+	return Magics
+}
+
+func (c *Codec) transform() transform {
+	if c != nil && c.Transform != nil {
+		return c.Transform
+	}
+	return Transform
+}
+
+func (c *Codec) maxDataSize() int32 {
+	if c != nil && c.MaxDataSize > 0 {
+		return c.MaxDataSize
+	}
+	return Options.MaxDataSize
+}
+
+// compress 包体长度为 size 时是否需要自动压缩
+func (c *Codec) compress(size int32) bool {
+	limit := Options.AutoCompressSize
+	if c != nil && c.AutoCompressSize != 0 {
+		limit = c.AutoCompressSize
+	}
+	return limit > 0 && size > limit
+}
+
+func (c *Codec) confirm() string {
+	if c != nil && c.S2CConfirm != "" {
+		return c.S2CConfirm
+	}
+	return Options.S2CConfirm
+}
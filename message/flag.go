@@ -11,6 +11,7 @@ const (
 	FlagCompressed                  // 是否压缩
 	FlagEncrypted                   // 是否加密
 	FlagFragmented                  // 分片包
+	FlagError                       // 错误包，和 FlagConfirm 一起使用，包体为标准错误结构
 )
 
 func (f Flag) Has(t Flag) bool {
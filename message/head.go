@@ -13,6 +13,7 @@ type Head struct {
 	flag  Flag  //1
 	size  int32 //4 BODY总长度(包含PATH) || code
 	index int32 //4 client_id,server_id
+	codec *Codec
 }
 
 func (h *Head) Flag() Flag {
@@ -29,7 +30,17 @@ func (h *Head) Index() int32 {
 }
 
 func (h *Head) Magic() *Magic {
-	return Magics[h.magic]
+	return h.codec.magics()[h.magic]
+}
+
+// Codec 消息绑定的编解码配置，nil 表示使用全局配置。
+func (h *Head) Codec() *Codec {
+	return h.codec
+}
+
+// SetCodec 绑定编解码配置，需要在 Parse、Reset 或 Marshal 之前调用。
+func (h *Head) SetCodec(c *Codec) {
+	h.codec = c
 }
 
 // Parse 解析二进制头并填充到对应字段
@@ -37,7 +48,7 @@ func (h *Head) Parse(head []byte) error {
 	if len(head) != messageHeadSize {
 		return ErrMsgHeadIllegal
 	}
-	magic := Magics.Get(head[0])
+	magic := h.codec.magics().Get(head[0])
 	if magic == nil {
 		return ErrMsgHeadIllegal
 	}
@@ -45,7 +56,7 @@ func (h *Head) Parse(head []byte) error {
 	h.flag = Flag(head[1])                           // 解析 tags 字段
 	h.size = int32(magic.Binary.Uint32(head[2:6]))   // 调整 size 字段位置
 	h.index = int32(magic.Binary.Uint32(head[6:10])) // 调整 index 字段位置
-	if h.size > Options.MaxDataSize {
+	if h.size > h.codec.maxDataSize() {
 		return ErrMsgDataSizeTooLong
 	}
 	return nil
@@ -60,7 +71,7 @@ func (h *Head) bytes() ([]byte, bool) {
 
 	// 检查是否需要添加压缩标记
 	compressed := false
-	if Options.AutoCompressSize > 0 && h.size > Options.AutoCompressSize && !flag.Has(FlagCompressed) {
+	if h.codec.compress(h.size) && !flag.Has(FlagCompressed) {
 		flag.Set(FlagCompressed)
 		compressed = true
 	}
@@ -76,7 +87,7 @@ func (h *Head) format(magic byte, flag Flag, index int32) (err error) {
 	h.flag = flag
 	h.index = index
 
-	mc := Magics.Get(h.magic)
+	mc := h.codec.magics().Get(h.magic)
 	if mc == nil {
 		return fmt.Errorf("message magic not exist,Magic:%d", h.magic)
 	}
@@ -95,4 +106,5 @@ func (h *Head) Release() {
 	h.flag = 0  // 重置 tags 字段
 	h.size = 0
 	h.index = 0
+	h.codec = nil
 }
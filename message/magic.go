@@ -45,6 +45,15 @@ func (ms magics) Get(key byte) *Magic {
 	return ms[key]
 }
 
+// Clone 复制魔数表，用于 Codec 独立注册魔数而不影响全局配置。
+func (ms magics) Clone() magics {
+	r := make(magics, len(ms))
+	for k, v := range ms {
+		r[k] = v
+	}
+	return r
+}
+
 func (ms magics) Register(key byte, mt MagicType, bi binder.Binder, by binary.ByteOrder) {
 	if _, ok := ms[key]; ok {
 		logger.Alert("Magic Number exists:%s", string(key))
@@ -40,7 +40,7 @@ func (m *message) Path() (r, q string, err error) {
 			r = path
 		}
 	} else {
-		r, err = Transform.Path(code)
+		r, err = m.codec.transform().Path(code)
 	}
 	return
 }
@@ -74,7 +74,7 @@ func (m *message) Bytes(w io.Writer, includeHeader bool) (n int, err error) {
 		}
 		n += r
 	} else {
-		compressed = Options.AutoCompressSize > 0 && size > Options.AutoCompressSize && !m.Head.flag.Has(FlagCompressed)
+		compressed = m.codec.compress(size) && !m.Head.flag.Has(FlagCompressed)
 	}
 	if size == 0 {
 		return
@@ -133,7 +133,7 @@ func (m *message) MarshalPath(magic *Magic, path string) (buffer *bytes.Buffer,
 		buffer.WriteString(path)
 	} else {
 		var code int32
-		if code, err = Transform.Code(path); err != nil {
+		if code, err = m.codec.transform().Code(path); err != nil {
 			return
 		}
 		magic.Binary.PutUint32(m.bytes[0:4], uint32(code))
@@ -145,7 +145,7 @@ func (m *message) MarshalPath(magic *Magic, path string) (buffer *bytes.Buffer,
 func (m *message) MarshalCode(magic *Magic, code int32) (buffer *bytes.Buffer, err error) {
 	if magic.Type == MagicTypePath {
 		var path string
-		if path, err = Transform.Path(code); err != nil {
+		if path, err = m.codec.transform().Path(code); err != nil {
 			return
 		}
 		magic.Binary.PutUint32(m.bytes[0:4], uint32(len(path)))
@@ -257,8 +257,8 @@ func (m *message) compress(w io.Writer) (int, error) {
 
 func (m *message) Confirm() string {
 	var p string
-	if Options.S2CConfirm != "" {
-		p = Options.S2CConfirm
+	if s := m.codec.confirm(); s != "" {
+		p = s
 	} else {
 		p, _, _ = m.Path()
 	}
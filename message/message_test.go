@@ -199,3 +199,39 @@ func TestMagicRegistry(t *testing.T) {
 		t.Errorf("MagicNumberPathJson type: got %d, want %d", m.Type, MagicTypePath)
 	}
 }
+
+// TestCodec 验证 Codec 独立的魔数和长度限制，不影响全局配置
+func TestCodec(t *testing.T) {
+	codec := &Codec{Magics: Magics.Clone(), MaxDataSize: 8}
+	codec.Magics.Register(0xe0, MagicTypePath, Magics.Get(MagicNumberPathJson).Binder, Magics.Get(MagicNumberPathJson).Binary)
+	if Magics.Has(0xe0) {
+		t.Fatal("codec magic leaked into global Magics")
+	}
+
+	m := Require(codec)
+	if err := m.Marshal(0xe0, 0, 1, "/a", []byte("hi")); err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if m.Codec() != codec {
+		t.Fatal("Require did not bind codec")
+	}
+
+	head := make([]byte, messageHeadSize)
+	head[0] = 0xe0
+	Magics.Get(MagicNumberPathJson).Binary.PutUint32(head[2:6], 16)
+	h := &Head{}
+	if err := h.Parse(head); err != ErrMsgHeadIllegal {
+		t.Errorf("global Parse should reject codec magic, got %v", err)
+	}
+	h.SetCodec(codec)
+	if err := h.Parse(head); err != ErrMsgDataSizeTooLong {
+		t.Errorf("codec MaxDataSize not applied, got %v", err)
+	}
+
+	Release(m)
+	m = Require()
+	if m.Codec() != nil {
+		t.Error("pooled message codec not reset")
+	}
+	Release(m)
+}
@@ -46,5 +46,7 @@ type Message interface {
 	Marshal(magic byte, flag Flag, index int32, pathOrCode any, body any) error //使用对象填充包体,pathOrCode: string(path) 或 int/int32/int64/uint/uint32/uint64(code)
 	Unmarshal(i any) (err error)                                                //解析包体
 	Confirm() string                                                            //确认包路径
+	Codec() *Codec                                                              //绑定的编解码配置，nil 表示使用全局配置
+	SetCodec(*Codec)                                                            //绑定编解码配置
 	Release()
 }
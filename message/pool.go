@@ -11,14 +11,18 @@ func init() {
 	}
 }
 
-func Require() Message {
+// Require 从消息池获取消息。
+// 参数 codec: 可选，消息绑定的编解码配置，不传时使用全局配置。
+func Require(codec ...*Codec) (m Message) {
 	if Options.Pool {
-		i := pool.Get()
-		return i.(Message)
+		m = pool.Get().(Message)
 	} else {
-		return Options.New()
+		m = Options.New()
 	}
-
+	if len(codec) > 0 && codec[0] != nil {
+		m.SetCodec(codec[0])
+	}
+	return m
 }
 
 func Release(i Message) {
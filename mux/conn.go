@@ -0,0 +1,67 @@
+package mux
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+)
+
+// ErrPeekLimit 识别协议读取的数据超过 Config.PeekMaxSize。
+var ErrPeekLimit = errors.New("mux peek limit exceeded")
+
+// sniffer 记录匹配过程中从连接读取的数据，每个 Matcher 都从头读取
+type sniffer struct {
+	conn net.Conn
+	buf  []byte
+	pos  int
+	max  int
+	err  error
+}
+
+func (s *sniffer) Read(p []byte) (int, error) {
+	if s.pos < len(s.buf) {
+		n := copy(p, s.buf[s.pos:])
+		s.pos += n
+		return n, nil
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	if len(s.buf) >= s.max {
+		return 0, ErrPeekLimit
+	}
+	if len(p) > s.max-len(s.buf) {
+		p = p[:s.max-len(s.buf)]
+	}
+	n, err := s.conn.Read(p)
+	s.buf = append(s.buf, p[:n]...)
+	s.pos += n
+	s.err = err
+	return n, err
+}
+
+// conn 先返回匹配时已经读取的数据，再从连接读取
+type conn struct {
+	net.Conn
+	buf []byte
+}
+
+func (c *conn) Read(p []byte) (int, error) {
+	if len(c.buf) > 0 {
+		n := copy(p, c.buf)
+		c.buf = c.buf[n:]
+		return n, nil
+	}
+	return c.Conn.Read(p)
+}
+
+// tlsConn 在 TLS 解密后匹配的连接，提供握手结果，HTTP 请求的 r.TLS 由该结果设置
+type tlsConn struct {
+	*conn
+	tls *tls.Conn
+}
+
+// ConnectionState 返回 TLS 连接状态，tcp.Conn 和 Mux.Handle 以外的使用者可以通过该方法获取对端证书。
+func (c *tlsConn) ConnectionState() tls.ConnectionState {
+	return c.tls.ConnectionState()
+}
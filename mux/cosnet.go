@@ -0,0 +1,37 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/tcp"
+	"github.com/hwcer/cosnet/wss"
+)
+
+// Sockets 将以 cosnet 魔数开头的连接交给 ss 处理（原生客户端），使用 ss.Options.Codec 识别魔数。
+// 返回值: 已经交给 ss.Accept 的监听器，ss 关闭时一起关闭
+func (m *Mux) Sockets(ss *cosnet.Sockets) (listener.Listener, error) {
+	ln, err := tcp.NewListener(m.Match(ss.Matcher), nil, &tcp.Config{})
+	if err != nil {
+		return nil, err
+	}
+	ss.Accept(ln)
+	return ln, nil
+}
+
+// WebSocket 将 WebSocket 握手请求交给 ss 处理（浏览器），使用 ss.Options.WSS 配置。
+// 参数 route: WebSocket 路径，为空时不限制路径
+// 返回值: 已经交给 ss.Accept 的监听器；ss.Options.WSS 开启 Forwarded 但 ForwardedTrusted 无效时返回错误
+func (m *Mux) WebSocket(ss *cosnet.Sockets, route string) (listener.Listener, error) {
+	ln, err := wss.NewListenerWithConfig(&http.Server{Addr: m.Addr().String()}, route, ss.Options.WSS)
+	if err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	m.upgrade = ln
+	m.registerHTTP()
+	m.mutex.Unlock()
+	ss.Accept(ln)
+	return ln, nil
+}
@@ -0,0 +1,54 @@
+package mux
+
+import (
+	"net"
+	"sync"
+)
+
+func newListener(m *Mux) *Listener {
+	return &Listener{mux: m, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+// Listener 接收 Mux 分发的连接，实现 net.Listener 接口。
+type Listener struct {
+	mux   *Mux
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Accept 等待并返回下一个匹配的连接，Listener 或 Mux 关闭后返回 net.ErrClosed。
+func (ln *Listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-ln.conns:
+		return c, nil
+	case <-ln.done:
+		return nil, net.ErrClosed
+	case <-ln.mux.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close 停止接收连接，之后匹配的连接被关闭，不影响 Mux 和其它监听器。
+func (ln *Listener) Close() error {
+	ln.once.Do(func() {
+		close(ln.done)
+	})
+	return nil
+}
+
+// Addr 返回 Mux 的监听地址。
+func (ln *Listener) Addr() net.Addr {
+	return ln.mux.Addr()
+}
+
+// deliver 交给 Accept，监听器关闭后关闭连接
+func (ln *Listener) deliver(c net.Conn) {
+	select {
+	case ln.conns <- c:
+	case <-ln.done:
+		_ = c.Close()
+	case <-ln.mux.done:
+		_ = c.Close()
+	}
+}
@@ -0,0 +1,56 @@
+package mux
+
+import (
+	"io"
+)
+
+// Matcher 检查连接开头的数据是否属于某种协议，与 cosnet.Matcher 的签名相同。
+// r 从连接开头读取数据，读取的数据会保留，匹配成功后原样交给对应的监听器；
+// 只应该读取判断所需的最少字节，对端数据不足时 Read 会阻塞到 Config.ReadTimeout。
+type Matcher func(r io.Reader) bool
+
+// Any 匹配所有连接，通常放在最后作为兜底。
+func Any() Matcher {
+	return func(io.Reader) bool {
+		return true
+	}
+}
+
+// Prefix 匹配以任意一个前缀开头的连接，逐字节读取，前缀不可能匹配时立即返回。
+func Prefix(prefixes ...string) Matcher {
+	return func(r io.Reader) bool {
+		var buf []byte
+		b := make([]byte, 1)
+		for {
+			possible := false
+			for _, p := range prefixes {
+				if len(p) <= len(buf) {
+					if string(buf[:len(p)]) == p {
+						return true
+					}
+					continue
+				}
+				if p[:len(buf)] == string(buf) {
+					possible = true
+				}
+			}
+			if !possible {
+				return false
+			}
+			if _, err := io.ReadFull(r, b); err != nil {
+				return false
+			}
+			buf = append(buf, b[0])
+		}
+	}
+}
+
+// HTTP 匹配 HTTP/1.x 请求（包括 WebSocket 握手）。
+func HTTP() Matcher {
+	return Prefix("GET ", "POST ", "PUT ", "DELETE ", "HEAD ", "OPTIONS ", "PATCH ", "CONNECT ", "TRACE ")
+}
+
+// TLS 匹配 TLS 握手（ClientHello 记录头 0x16 0x03）。
+func TLS() Matcher {
+	return Prefix("\x16\x03")
+}
@@ -0,0 +1,270 @@
+package mux
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hwcer/cosgo/scc"
+	"github.com/hwcer/logger"
+)
+
+// Listen 监听地址并创建端口复用器。
+// 参数:
+//   - network: "tcp", "tcp4", "tcp6"
+//   - address: 监听地址
+func Listen(network, address string) (*Mux, error) {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return New(ln), nil
+}
+
+// New 使用已经创建的监听器创建端口复用器。
+func New(ln net.Listener) *Mux {
+	return NewWithConfig(ln, nil)
+}
+
+// NewWithConfig 使用指定配置创建端口复用器，config 为 nil 时使用 Options
+func NewWithConfig(ln net.Listener, config *Config) *Mux {
+	if config == nil {
+		config = &Options
+	}
+	return &Mux{root: ln, config: config, done: make(chan struct{})}
+}
+
+// Mux 端口复用器，读取每个新连接开头的数据，按注册顺序依次使用 Matcher 识别协议，
+// 交给第一个匹配的监听器；都不匹配时关闭连接。
+type Mux struct {
+	root    net.Listener
+	config  *Config
+	mutex   sync.RWMutex
+	routes  []*route
+	tls     *tls.Config
+	http    *route // HTTP 请求（包括 WebSocket）共用的路由
+	server  *http.Server
+	handler http.Handler
+	upgrade http.Handler // WebSocket 握手请求的处理器
+	done    chan struct{}
+	once    sync.Once
+}
+
+// route 一组 Matcher 及其监听器，tls 为 true 时匹配成功后完成 TLS 握手并重新匹配
+type route struct {
+	matchers []Matcher
+	listener *Listener
+	tls      bool
+}
+
+func (r *route) match(s *sniffer) bool {
+	for _, m := range r.matchers {
+		s.pos = 0
+		if m(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Addr 返回监听地址。
+func (m *Mux) Addr() net.Addr {
+	return m.root.Addr()
+}
+
+// Match 注册一组 Matcher，任意一个匹配的连接交给返回的监听器。
+// 按注册顺序匹配，先注册的优先，Any 等宽泛的 Matcher 应该最后注册。
+// 参数 matchers: 协议识别函数，例如 cosnet.Sockets.Matcher、HTTP()、Prefix("SSH-")
+// 返回值: 接收匹配连接的监听器
+func (m *Mux) Match(matchers ...Matcher) *Listener {
+	r := &route{matchers: matchers, listener: newListener(m)}
+	m.mutex.Lock()
+	m.routes = append(m.routes, r)
+	m.mutex.Unlock()
+	return r.listener
+}
+
+// TLS 注册 TLS 连接，握手完成后使用解密后的数据重新匹配其它路由，
+// 因此同一个端口上原生客户端、WebSocket 和 HTTP 都可以同时使用明文和 TLS。
+// 参数 config: TLS 配置，SNI 证书选择和证书重新加载参考 listener.Certificates
+func (m *Mux) TLS(config *tls.Config) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.tls = config
+	m.routes = append(m.routes, &route{matchers: []Matcher{TLS()}, tls: true})
+}
+
+// Handle 设置处理 HTTP 请求的处理器，例如健康检查，WebSocket 握手请求由 WebSocket 处理。
+// 第一次调用 Handle 或 WebSocket 时注册 HTTP 路由，需要在 Serve（Start）之前调用。
+func (m *Mux) Handle(handler http.Handler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.handler = handler
+	m.registerHTTP()
+}
+
+// registerHTTP 注册 HTTP 路由，调用者需要持有锁
+func (m *Mux) registerHTTP() {
+	if m.http != nil {
+		return
+	}
+	m.http = &route{matchers: []Matcher{HTTP()}, listener: newListener(m)}
+	m.routes = append(m.routes, m.http)
+	m.server = &http.Server{Handler: http.HandlerFunc(m.serveHTTP), ReadHeaderTimeout: m.config.ReadTimeout, ConnContext: connContext}
+}
+
+// connKey 在请求的 context 中保存连接，用于恢复 TLS 连接状态
+type connKey struct{}
+
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, c)
+}
+
+func (m *Mux) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// http.Server 只为 *tls.Conn 设置 r.TLS，TLS 解密后重新匹配的连接需要手动补上
+	if tc, ok := r.Context().Value(connKey{}).(*tlsConn); ok && r.TLS == nil {
+		state := tc.ConnectionState()
+		r.TLS = &state
+	}
+	m.mutex.RLock()
+	upgrade, handler := m.upgrade, m.handler
+	m.mutex.RUnlock()
+	if upgrade != nil && websocket.IsWebSocketUpgrade(r) {
+		upgrade.ServeHTTP(w, r)
+	} else if handler != nil {
+		handler.ServeHTTP(w, r)
+	} else {
+		http.NotFound(w, r)
+	}
+}
+
+// Start 在后台接受连接，进程退出时关闭。
+func (m *Mux) Start() {
+	scc.CGO(func(ctx context.Context) {
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = m.Close()
+			case <-m.done:
+			}
+		}()
+		if err := m.Serve(); err != nil {
+			logger.Alert("mux serve error:%v", err)
+		}
+	})
+}
+
+// Serve 接受连接并分发，阻塞直到 Close。
+// Accept 出错（例如文件描述符耗尽）时按 5ms 到 1s 指数退避后重试，与 http.Server.Serve 相同。
+// 返回值: Close 后返回 nil，监听器被关闭（net.ErrClosed）时返回该错误
+func (m *Mux) Serve() error {
+	m.mutex.RLock()
+	server, hl := m.server, m.http
+	m.mutex.RUnlock()
+	if server != nil {
+		go func() {
+			if err := server.Serve(hl.listener); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+				logger.Alert("mux http serve error:%v", err)
+			}
+		}()
+	}
+	var delay time.Duration
+	for {
+		c, err := m.root.Accept()
+		if err != nil {
+			select {
+			case <-m.done:
+				return nil
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			if delay == 0 {
+				delay = 5 * time.Millisecond
+			} else if delay *= 2; delay > time.Second {
+				delay = time.Second
+			}
+			logger.Debug("mux accept error:%v; retrying in %v", err, delay)
+			select {
+			case <-time.After(delay):
+			case <-m.done:
+				return nil
+			}
+			continue
+		}
+		delay = 0
+		go m.dispatch(c, nil)
+	}
+}
+
+// Close 关闭监听器和所有子监听器。
+func (m *Mux) Close() (err error) {
+	m.once.Do(func() {
+		close(m.done)
+		err = m.root.Close()
+		m.mutex.RLock()
+		server := m.server
+		m.mutex.RUnlock()
+		if server != nil {
+			_ = server.Close()
+		}
+	})
+	return
+}
+
+// dispatch 识别协议并交给匹配的监听器，decrypted 为 TLS 解密后的连接
+func (m *Mux) dispatch(c net.Conn, decrypted *tls.Conn) {
+	_ = c.SetReadDeadline(time.Now().Add(m.config.ReadTimeout))
+	s := &sniffer{conn: c, max: m.config.PeekMaxSize}
+	m.mutex.RLock()
+	routes := m.routes
+	m.mutex.RUnlock()
+	var target *route
+	for _, r := range routes {
+		if r.tls && decrypted != nil {
+			continue
+		}
+		if r.match(s) {
+			target = r
+			break
+		}
+	}
+	if target == nil {
+		logger.Debug("mux unknown protocol %v error:%v", c.RemoteAddr(), s.err)
+		_ = c.Close()
+		return
+	}
+	_ = c.SetReadDeadline(time.Time{})
+	wrapped := &conn{Conn: c, buf: s.buf}
+	if target.tls {
+		m.handshake(wrapped)
+		return
+	}
+	if decrypted != nil {
+		target.listener.deliver(&tlsConn{conn: wrapped, tls: decrypted})
+	} else {
+		target.listener.deliver(wrapped)
+	}
+}
+
+func (m *Mux) handshake(c net.Conn) {
+	m.mutex.RLock()
+	config := m.tls
+	m.mutex.RUnlock()
+	ctx, cancel := context.WithTimeout(context.Background(), m.config.TLSHandshakeTimeout)
+	tc := tls.Server(c, config)
+	err := tc.HandshakeContext(ctx)
+	cancel()
+	if err != nil {
+		logger.Debug("mux tls handshake %v error:%v", c.RemoteAddr(), err)
+		_ = c.Close()
+		return
+	}
+	m.dispatch(tc, tc)
+}
@@ -0,0 +1,188 @@
+package mux
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosnet"
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/cosnet/wss"
+)
+
+func TestMux(t *testing.T) {
+	hs := httptest.NewUnstartedServer(nil)
+	hs.StartTLS()
+	cert := hs.TLS.Certificates[0]
+	hs.Close()
+
+	ss := cosnet.New()
+	got := make(chan bool, 8)
+	ss.On(cosnet.EventTypeMessage, func(s *cosnet.Socket, v any) {
+		_, secure := s.TLS()
+		got <- secure
+	})
+	if err := ss.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	m.TLS(&tls.Config{Certificates: []tls.Certificate{cert}})
+	if _, err = m.Sockets(ss); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.WebSocket(ss, "/ws"); err != nil {
+		t.Fatal(err)
+	}
+	m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			_, _ = w.Write([]byte("tls"))
+		} else {
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	other := m.Match(Prefix("SSH-"))
+	go func() { _ = m.Serve() }()
+	addr := m.Addr().String()
+
+	cl := cosnet.New()
+	for _, tc := range []struct {
+		address string
+		opts    *cosnet.DialOptions
+		secure  bool
+	}{
+		{address: "tcp://" + addr},
+		{address: "ws://" + addr + "/ws"},
+		{address: "tls://" + addr, opts: &cosnet.DialOptions{TLSConfig: &tls.Config{InsecureSkipVerify: true}}, secure: true},
+	} {
+		sock, err := cl.Connect(tc.address, tc.opts)
+		if err != nil {
+			t.Fatalf("%v:%v", tc.address, err)
+		}
+		_ = sock.Send(message.FlagNoreply, 0, "/ping", nil)
+		select {
+		case secure := <-got:
+			if secure != tc.secure {
+				t.Fatalf("%v tls state %v", tc.address, secure)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%v message timeout", tc.address)
+		}
+		sock.Close()
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	for url, want := range map[string]string{"http://" + addr + "/health": "ok", "https://" + addr + "/health": "tls"} {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("%v:%v", url, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if string(body) != want {
+			t.Fatalf("%v body %q", url, body)
+		}
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	_, _ = conn.Write([]byte("SSH-2.0-test\r\n"))
+	c, err := other.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := make([]byte, 14)
+	if _, err = io.ReadFull(c, line); err != nil || !strings.HasPrefix(string(line), "SSH-2.0") {
+		t.Fatalf("custom matcher data %q %v", line, err)
+	}
+	_ = c.Close()
+}
+
+func TestPrefix(t *testing.T) {
+	m := Prefix("GET ", "GEX")
+	s := &sniffer{conn: nil, buf: []byte("GEX"), max: 16}
+	if !m(s) {
+		t.Fatal("prefix not matched")
+	}
+	s = &sniffer{conn: nil, buf: []byte{0xf0, 1}, max: 16}
+	if m(s) || s.pos != 1 {
+		t.Fatalf("prefix read %d bytes", s.pos)
+	}
+}
+
+// flakyListener 前几次 Accept 返回错误，模拟文件描述符耗尽
+type flakyListener struct {
+	net.Listener
+	fails int
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if l.fails > 0 {
+		l.fails--
+		return nil, errors.New("accept: too many open files")
+	}
+	return l.Listener.Accept()
+}
+
+func TestServeRetry(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(&flakyListener{Listener: ln, fails: 3})
+	other := m.Match(Any())
+	done := make(chan error, 1)
+	go func() { done <- m.Serve() }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	_, _ = conn.Write([]byte("x"))
+	c, err := other.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Close()
+
+	_ = m.Close()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatalf("serve returned %v after close", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve not returned after close")
+	}
+}
+
+func TestWebSocketForwarded(t *testing.T) {
+	m, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	ss := cosnet.New()
+	ss.Options.WSS = &wss.Config{Forwarded: true}
+	if _, err = m.WebSocket(ss, "/ws"); !errors.Is(err, listener.ErrTrustedRequired) {
+		t.Fatalf("Forwarded without ForwardedTrusted:%v", err)
+	}
+	if m.upgrade != nil {
+		t.Fatal("invalid WebSocket registered")
+	}
+}
@@ -0,0 +1,20 @@
+package mux
+
+import "time"
+
+// Config 端口复用配置，可以通过 NewWithConfig 为每个监听器单独设置
+type Config struct {
+	// ReadTimeout 识别协议的超时时间，超时仍未匹配的连接被关闭
+	ReadTimeout time.Duration
+	// PeekMaxSize 识别协议时最多读取的字节数
+	PeekMaxSize int
+	// TLSHandshakeTimeout TLS 握手超时时间
+	TLSHandshakeTimeout time.Duration
+}
+
+// Options 端口复用默认配置选项
+var Options = Config{
+	ReadTimeout:         5 * time.Second, // 5 秒内无法识别协议关闭连接
+	PeekMaxSize:         1024,            // 最多读取 1KB 识别协议
+	TLSHandshakeTimeout: 5 * time.Second, // 5 秒内未完成 TLS 握手关闭连接
+}
@@ -0,0 +1,76 @@
+package cosnet
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// testServerSocket 获取服务器上唯一的连接
+func testServerSocket(t *testing.T, ss *Sockets) (sock *Socket) {
+	t.Helper()
+	testEventually(t, testTimeout, func() bool {
+		ss.Range(func(s *Socket) bool {
+			sock = s
+			return false
+		})
+		// Range 可能在 connect 完成之前返回 Socket，等待连接完成后再操作
+		return sock != nil && sock.Status() == SocketStatusConnected
+	})
+	return
+}
+
+func TestNotFoundPolicy(t *testing.T) {
+	setup := func(policy NotFoundPolicy) func(ss *Sockets) {
+		return func(ss *Sockets) {
+			ss.Options.NotFoundPolicy = policy
+			ss.Options.NotFoundMaxCount = 2
+			_ = ss.Service().Register(func(c *Context) any {
+				return []byte("pong")
+			}, "/ping")
+		}
+	}
+
+	t.Run("event", func(t *testing.T) {
+		var events int32
+		_, address := testServer(t, setup(NotFoundPolicyEvent), func(ss *Sockets) {
+			ss.On(EventTypeMessage, func(*Socket, any) {
+				atomic.AddInt32(&events, 1)
+			})
+		})
+		sock, replies := testClient(t, address)
+		_ = sock.Send(0, 1, "/unknown", nil)
+		_ = sock.Send(0, 2, "/ping", nil)
+		if r := testReceive(t, replies); r.index != 2 {
+			t.Fatalf("unexpected reply %+v", r)
+		}
+		if n := atomic.LoadInt32(&events); n != 1 {
+			t.Fatalf("message events %d", n)
+		}
+	})
+
+	t.Run("reply", func(t *testing.T) {
+		_, address := testServer(t, setup(NotFoundPolicyReply))
+		sock, replies := testClient(t, address)
+		_ = sock.Send(0, 1, "/unknown", nil)
+		r := testReceive(t, replies)
+		if e := testError(t, r); e == nil || e.Code != ErrorCodeNotFound || r.index != 1 {
+			t.Fatalf("not found reply %+v", r)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		ss, address := testServer(t, setup(NotFoundPolicyClose))
+		sock, replies := testClient(t, address)
+		server := testServerSocket(t, ss)
+		_ = sock.Send(0, 1, "/unknown", nil)
+		testReceive(t, replies)
+		if status := atomic.LoadInt32(&server.status); status != SocketStatusConnected {
+			t.Fatalf("closed before NotFoundMaxCount,status:%d", status)
+		}
+		_ = sock.Send(0, 2, "/unknown", nil)
+		testReceive(t, replies)
+		testEventually(t, testTimeout, func() bool {
+			return atomic.LoadInt32(&server.status) != SocketStatusConnected
+		})
+	})
+}
@@ -1,8 +1,25 @@
 package cosnet
 
+import (
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/cosnet/tcp"
+	"github.com/hwcer/cosnet/udp"
+	"github.com/hwcer/cosnet/wss"
+)
+
 // RegistryMethod 注册方法名称，默认为"TCP"
 const RegistryMethod = "TCP"
 
+// NotFoundPolicy 定义路由不存在时的处理策略。
+type NotFoundPolicy int8
+
+// 路由不存在时的处理策略常量，仅作用于服务器模式的 Socket，均会触发 EventTypeMessage 事件。
+const (
+	NotFoundPolicyEvent NotFoundPolicy = iota // 仅触发事件（默认）
+	NotFoundPolicyReply                       // 触发事件并回复 ErrorCodeNotFound 错误包
+	NotFoundPolicyClose                       // 回复错误包并计数，达到 NotFoundMaxCount 次时关闭连接
+)
+
 type Config struct {
 	// Heartbeat 服务器心跳间隔，单位秒，用来检测玩家僵尸连接
 	Heartbeat int32
@@ -14,23 +31,85 @@ type Config struct {
 	SocketConnectTime int32
 	// SocketReplacedTime 顶号延时关闭时间，单位秒
 	SocketReplacedTime int32
+	// SocketHandshakeTime 服务器模式下未完成身份认证的连接没有动作被判断为掉线的时间，单位秒，0 表示使用 SocketConnectTime
+	// 按空闲时间计算，收到任何消息都会重新计时；与 AuthenticationTimeout 同时生效，先满足的一方关闭连接
+	SocketHandshakeTime int32
+	// SocketRoleKey 用户会话数据中角色字段的名称，为空时不按角色区分超时
+	SocketRoleKey string
+	// SocketRoleTime 按角色设置没有动作被判断为掉线的时间，单位秒，未配置的角色使用 SocketConnectTime
+	SocketRoleTime map[string]int32
+	// HandleMode 消息处理模式，默认在读协程中直接处理
+	HandleMode HandleMode
+	// HandleQueueSize 消息处理队列长度，队列满时丢弃消息并触发 EventTypeOverload
+	HandleQueueSize int32
+	// HandleWorkerSize 共享工作池的协程数量（HandleModeKeyed 模式下为分片数量），0 表示使用 CPU 核数
+	HandleWorkerSize int32
+	// EventQueueSize 异步事件监听器的默认队列长度
+	EventQueueSize int32
+	// NotFoundPolicy 路由不存在时的处理策略
+	NotFoundPolicy NotFoundPolicy
+	// NotFoundMaxCount NotFoundPolicyClose 策略下单个连接允许的最大未知路由次数，达到后关闭连接
+	NotFoundMaxCount int32
+	// AuthenticationRequired 未声明 Public/Protected 的路由是否默认需要身份认证
+	AuthenticationRequired bool
+	// AuthenticationTimeout 连接后未完成身份认证的最长时间，单位秒，超时关闭连接，0 表示不限制
+	// 从连接建立开始计算，收到消息不会重新计时，超时时触发 EventTypeUnauthorized；与 SocketHandshakeTime 同时生效，先满足的一方关闭连接
+	AuthenticationTimeout int32
+	// AcceptRejectPath 连接被准入检查拒绝时发送给对端的错误包路径，为空时直接关闭连接
+	AcceptRejectPath string
 
+	// HeartbeatPath 心跳包路径，服务器对未注册该路由的心跳包自动回应
+	HeartbeatPath string
+	// ClientHeartbeat 客户端主动心跳间隔，单位秒，0 表示不发送（默认）
+	// 开启前需确认服务器能够回应 HeartbeatPath 心跳包（cosnet 服务器会自动回应），否则会被判定为心跳超时
+	ClientHeartbeat int32
+	// ClientHeartbeatTimeout 客户端超过该时间未收到心跳回应则断开重连，单位秒，0 表示不检测（默认）
+	// 仅 ClientHeartbeat > 0 时生效，建议设置为 ClientHeartbeat 的 3~4 倍
+	ClientHeartbeatTimeout int32
+
+	// ClientDialTimeout 客户端连接服务器的超时时间，单位毫秒
+	ClientDialTimeout int32
 	// ClientReconnectMax 断线重连最大尝试次数，0 表示无限尝试
 	ClientReconnectMax int32
 	// ClientReconnectTime 断线重连基础等待时间，单位毫秒，实际等待时间为 ClientReconnectTime * 重连次数
 	ClientReconnectTime int32
 	// ClientReconnectMaxDelay 断线重连指数退避的最大等待时间，单位毫秒
 	ClientReconnectMaxDelay int32
+	// ClientReconnectJitter 断线重连等待时间的随机浮动比例，单位百分比，例如 20 表示 ±20%，0 表示不浮动
+	ClientReconnectJitter int32
+
+	// NodeId 集群节点编号，写入 Socket ID 的高 16 位，保证集群内 Socket ID 唯一，单机部署保持 0
+	NodeId uint16
+
+	// Codec 消息编解码配置（魔数、长度限制、压缩、路径转换），nil 表示使用 message 包的全局配置
+	Codec *message.Codec
+	// TCP TCP 监听使用的配置（PROXY protocol），nil 表示使用 tcp.Options
+	TCP *tcp.Config
+	// WSS WebSocket 监听和连接使用的配置，nil 表示使用 wss.Options
+	WSS *wss.Config
+	// UDP UDP 监听和连接使用的配置（包括可靠模式），nil 表示使用 udp.Options
+	UDP *udp.Config
 }
 
 // Options 配置选项结构体
 var Options = Config{
-	Heartbeat:               10,     // 心跳间隔 10 秒
-	WriteChanSize:           100,    // 写通道缓存 100 条消息
-	ConnectMaxSize:          100000, // 最大连接数 10 万
-	SocketConnectTime:       30,     // 30 秒无动作判断为掉线
-	SocketReplacedTime:      5,      // 顶号后 5 秒关闭旧连接
-	ClientReconnectMax:      10,     // 最大重连尝试 10 次
-	ClientReconnectTime:     1000,   // 基础重连等待 1 秒（指数退避）
-	ClientReconnectMaxDelay: 30000,  // 最大等待时间 30 秒
+	Heartbeat:               10,           // 心跳间隔 10 秒
+	WriteChanSize:           100,          // 写通道缓存 100 条消息
+	ConnectMaxSize:          100000,       // 最大连接数 10 万
+	SocketConnectTime:       30,           // 30 秒无动作判断为掉线
+	SocketReplacedTime:      5,            // 顶号后 5 秒关闭旧连接
+	HandleQueueSize:         1000,         // 处理队列 1000 条消息
+	HandleWorkerSize:        0,            // 工作协程数量为 CPU 核数
+	EventQueueSize:          1000,         // 异步事件队列 1000 条
+	NotFoundMaxCount:        10,           // 第 10 次未知路由时关闭连接（NotFoundPolicyClose）
+	AuthenticationRequired:  false,        // 路由默认公开，兼容旧版本
+	AuthenticationTimeout:   0,            // 默认不限制未认证连接的存活时间
+	HeartbeatPath:           "/heartbeat", // 心跳包路径
+	ClientHeartbeat:         0,            // 默认不发送客户端心跳，兼容不回应心跳包的旧服务器
+	ClientHeartbeatTimeout:  0,            // 默认不检测客户端心跳超时
+	ClientDialTimeout:       1000,         // 连接超时 1 秒
+	ClientReconnectMax:      10,           // 最大重连尝试 10 次
+	ClientReconnectTime:     1000,         // 基础重连等待 1 秒（指数退避）
+	ClientReconnectMaxDelay: 30000,        // 最大等待时间 30 秒
+	ClientReconnectJitter:   20,           // 等待时间随机浮动 ±20%，避免大量客户端同时重连
 }
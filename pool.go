@@ -0,0 +1,378 @@
+package cosnet
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hwcer/cosgo/scc"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// ErrPoolUnavailable 连接池中没有可用的连接。
+var ErrPoolUnavailable = errors.New("client pool unavailable")
+
+// Provider 连接池的服务器地址来源，可以是固定列表，也可以对接服务发现。
+type Provider interface {
+	// Addresses 返回当前所有服务器地址，格式与 Sockets.Connect 相同。
+	Addresses() ([]string, error)
+}
+
+// StaticProvider 固定的服务器地址列表。
+type StaticProvider []string
+
+// Addresses 实现 Provider 接口。
+func (p StaticProvider) Addresses() ([]string, error) {
+	return p, nil
+}
+
+// ProviderFunc 使用函数实现 Provider 接口。
+type ProviderFunc func() ([]string, error)
+
+// Addresses 实现 Provider 接口。
+func (f ProviderFunc) Addresses() ([]string, error) {
+	return f()
+}
+
+// BalanceMode 定义连接池选择连接的方式。
+type BalanceMode int8
+
+// 负载均衡方式常量。
+const (
+	BalanceModeRoundRobin   BalanceMode = iota // 轮询（默认）
+	BalanceModeLeastPending                    // 选择写通道中待发送消息最少的连接
+	BalanceModeHash                            // 按 key 一致性哈希，同一个 key 固定到同一个服务器，服务器不可用时转移到下一个
+)
+
+// ClientPoolOptions 连接池配置。
+type ClientPoolOptions struct {
+	Size     int32         // 每个服务器保持的连接数量，默认 1
+	Balance  BalanceMode   // 负载均衡方式
+	Dialer   *DialOptions  // 连接选项
+	Cooldown time.Duration // 服务器被标记为不健康后暂停使用的时间，默认 5 秒
+	Refresh  time.Duration // 刷新地址列表和补充连接的间隔，默认 10 秒
+}
+
+// ClientPool 客户端连接池，对多个服务器分别保持 N 个连接，按负载均衡方式选择连接并自动故障转移。
+type ClientPool struct {
+	index    uint64
+	mutex    sync.RWMutex
+	refresh  sync.Mutex
+	started  atomic.Bool
+	sockets  *Sockets
+	provider Provider
+	backends map[string]*poolBackend
+	list     []*poolBackend // 按地址排序的 backends，保证轮询和哈希的遍历顺序稳定
+	cancel   context.CancelFunc
+	Options  ClientPoolOptions
+}
+
+// poolBackend 一个服务器及其连接。
+type poolBackend struct {
+	address   string
+	sockets   []*Socket
+	unhealthy atomic.Int64 // 不健康状态的截止时间，UnixNano
+}
+
+// healthy 服务器是否可用。
+func (b *poolBackend) healthy(now int64) bool {
+	return b.unhealthy.Load() <= now
+}
+
+// NewClientPool 创建连接池，调用 Start 后开始连接。
+// 参数:
+//   - ss: 创建连接使用的 Sockets 实例，连接的事件和路由由其处理
+//   - provider: 服务器地址来源
+//   - opts: 连接池配置，可选
+func NewClientPool(ss *Sockets, provider Provider, opts ...ClientPoolOptions) *ClientPool {
+	p := &ClientPool{sockets: ss, provider: provider, backends: map[string]*poolBackend{}}
+	if len(opts) > 0 {
+		p.Options = opts[0]
+	}
+	if p.Options.Size <= 0 {
+		p.Options.Size = 1
+	}
+	if p.Options.Cooldown <= 0 {
+		p.Options.Cooldown = 5 * time.Second
+	}
+	if p.Options.Refresh <= 0 {
+		p.Options.Refresh = 10 * time.Second
+	}
+	return p
+}
+
+// Start 连接所有服务器并启动定时刷新。
+// 返回值: 获取地址列表失败时返回错误。
+func (p *ClientPool) Start() error {
+	if !p.started.CompareAndSwap(false, true) {
+		return nil
+	}
+	err := p.Refresh()
+	ctx, cancel := scc.WithCancel()
+	p.cancel = cancel
+	scc.SGO(func(_ context.Context) {
+		p.daemon(ctx)
+	})
+	return err
+}
+
+// Close 停止刷新并关闭所有连接。
+func (p *ClientPool) Close() {
+	if !p.started.CompareAndSwap(true, false) {
+		return
+	}
+	p.cancel()
+	p.mutex.Lock()
+	backends := p.backends
+	p.backends = map[string]*poolBackend{}
+	p.list = nil
+	p.mutex.Unlock()
+	for _, b := range backends {
+		p.closeBackend(b)
+	}
+}
+
+func (p *ClientPool) daemon(ctx context.Context) {
+	ticker := time.NewTicker(p.Options.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Refresh(); err != nil {
+				logger.Alert("client pool refresh error:%v", err)
+			}
+		}
+	}
+}
+
+// Refresh 重新获取地址列表，连接新增的服务器，关闭被移除的服务器，并补充已经失效的连接。
+// 返回值: 获取地址列表失败时返回错误，已有连接不受影响。
+func (p *ClientPool) Refresh() error {
+	p.refresh.Lock()
+	defer p.refresh.Unlock()
+	addresses, err := p.provider.Addresses()
+	if err != nil {
+		return err
+	}
+	dict := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		dict[address] = true
+	}
+	var removed []*poolBackend
+	p.mutex.Lock()
+	for address, b := range p.backends {
+		if !dict[address] {
+			removed = append(removed, b)
+			delete(p.backends, address)
+		}
+	}
+	var backends []*poolBackend
+	for address := range dict {
+		b := p.backends[address]
+		if b == nil {
+			b = &poolBackend{address: address}
+			p.backends[address] = b
+		}
+		backends = append(backends, b)
+	}
+	sort.Slice(backends, func(i, j int) bool {
+		return backends[i].address < backends[j].address
+	})
+	p.list = backends
+	p.mutex.Unlock()
+
+	for _, b := range removed {
+		p.closeBackend(b)
+	}
+	for _, b := range backends {
+		p.fill(b)
+	}
+	return nil
+}
+
+// fill 补充服务器的连接，只尝试一次，失败时标记为不健康。
+func (p *ClientPool) fill(b *poolBackend) {
+	p.mutex.RLock()
+	sockets := make([]*Socket, 0, p.Options.Size)
+	for _, sock := range b.sockets {
+		if sock.Status() != SocketStatusReleased {
+			sockets = append(sockets, sock)
+		}
+	}
+	p.mutex.RUnlock()
+	for int32(len(sockets)) < p.Options.Size {
+		conn, err := p.sockets.dial(b.address, p.Options.Dialer)
+		if err == nil {
+			var sock *Socket
+			if sock, err = p.sockets.create(conn, b.address, nil, p.Options.Dialer); err == nil {
+				sockets = append(sockets, sock)
+				continue
+			}
+		}
+		logger.Debug("client pool connect %v error:%v", b.address, err)
+		p.markUnhealthy(b)
+		break
+	}
+	p.mutex.Lock()
+	b.sockets = sockets
+	p.mutex.Unlock()
+}
+
+func (p *ClientPool) closeBackend(b *poolBackend) {
+	p.mutex.RLock()
+	sockets := b.sockets
+	p.mutex.RUnlock()
+	for _, sock := range sockets {
+		sock.shutdown()
+	}
+}
+
+func (p *ClientPool) markUnhealthy(b *poolBackend) {
+	b.unhealthy.Store(time.Now().Add(p.Options.Cooldown).UnixNano())
+}
+
+// MarkUnhealthy 将连接所在的服务器标记为不健康，在 Options.Cooldown 时间内不再选择。
+// 参数 sock: 连接池中的连接。
+func (p *ClientPool) MarkUnhealthy(sock *Socket) {
+	p.mutex.RLock()
+	b := p.backends[sock.address]
+	p.mutex.RUnlock()
+	if b != nil {
+		p.markUnhealthy(b)
+	}
+}
+
+// Get 按负载均衡方式选择一个可用的连接。
+// 参数 key: 一致性哈希使用的 key，其它方式忽略。
+// 返回值:
+//   - socket: 选中的连接
+//   - err: 没有可用连接时返回 ErrPoolUnavailable
+func (p *ClientPool) Get(key ...string) (*Socket, error) {
+	var k string
+	if len(key) > 0 {
+		k = key[0]
+	}
+	now := time.Now().UnixNano()
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	var sock *Socket
+	switch p.Options.Balance {
+	case BalanceModeHash:
+		sock = p.hash(k, now)
+	case BalanceModeLeastPending:
+		p.rangeReady(now, func(s *Socket) {
+			if sock == nil || s.Pending() < sock.Pending() {
+				sock = s
+			}
+		})
+	default:
+		var ready []*Socket
+		p.rangeReady(now, func(s *Socket) {
+			ready = append(ready, s)
+		})
+		if n := len(ready); n > 0 {
+			sock = ready[atomic.AddUint64(&p.index, 1)%uint64(n)]
+		}
+	}
+	if sock == nil {
+		return nil, ErrPoolUnavailable
+	}
+	return sock, nil
+}
+
+// rangeReady 遍历健康服务器中所有已连接的连接。
+func (p *ClientPool) rangeReady(now int64, fn func(*Socket)) {
+	for _, b := range p.list {
+		if !b.healthy(now) {
+			continue
+		}
+		for _, s := range b.sockets {
+			if s.IsReady() {
+				fn(s)
+			}
+		}
+	}
+}
+
+// hash 使用最高随机权重（rendezvous）哈希选择服务器，服务器增减时只影响落在该服务器上的 key。
+func (p *ClientPool) hash(key string, now int64) *Socket {
+	var best []*Socket
+	var weight uint64
+	for _, b := range p.list {
+		if !b.healthy(now) {
+			continue
+		}
+		var ready []*Socket
+		for _, s := range b.sockets {
+			if s.IsReady() {
+				ready = append(ready, s)
+			}
+		}
+		if len(ready) == 0 {
+			continue
+		}
+		if w := hashKey(key + "@" + b.address); best == nil || w > weight {
+			best, weight = ready, w
+		}
+	}
+	if len(best) == 0 {
+		return nil
+	}
+	return best[hashKey(key)%uint64(len(best))]
+}
+
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// Send 选择一个连接发送消息，发送失败时将服务器标记为不健康并转移到其它服务器。
+// 参数:
+//   - key: 一致性哈希使用的 key，其它方式可以为空
+//   - flag: 消息标志
+//   - index: 消息序号
+//   - path: 消息路径
+//   - data: 消息数据
+//
+// 返回值: 所有服务器都不可用时返回最后一次的错误
+func (p *ClientPool) Send(key string, flag message.Flag, index int32, path any, data any) (err error) {
+	p.mutex.RLock()
+	n := len(p.backends)
+	p.mutex.RUnlock()
+	for i := 0; i < n; i++ {
+		var sock *Socket
+		if sock, err = p.Get(key); err != nil {
+			return err
+		}
+		if err = sock.Send(flag, index, path, data); err == nil {
+			return nil
+		}
+		p.MarkUnhealthy(sock)
+	}
+	if err == nil {
+		err = ErrPoolUnavailable
+	}
+	return err
+}
+
+// Range 遍历连接池中的所有连接。
+// 参数 fn: 遍历回调函数，参数为服务器地址和连接，返回 false 时停止遍历。
+func (p *ClientPool) Range(fn func(address string, sock *Socket) bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for address, b := range p.backends {
+		for _, s := range b.sockets {
+			if !fn(address, s) {
+				return
+			}
+		}
+	}
+}
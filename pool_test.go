@@ -0,0 +1,106 @@
+package cosnet
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientPoolFailover(t *testing.T) {
+	_, a := testServer(t)
+	_, b := testServer(t)
+	down := "tcp://127.0.0.1:1"
+	pool := NewClientPool(New(), StaticProvider{a, b, down}, ClientPoolOptions{
+		Size:     2,
+		Balance:  BalanceModeHash,
+		Dialer:   &DialOptions{Timeout: time.Second},
+		Cooldown: time.Minute,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	counts := map[string]int{}
+	pool.Range(func(address string, sock *Socket) bool {
+		counts[address]++
+		return true
+	})
+	if counts[a] != 2 || counts[b] != 2 || counts[down] != 0 {
+		t.Fatalf("pool sockets %v", counts)
+	}
+
+	first, err := pool.Get("uid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if sock, _ := pool.Get("uid"); sock.address != first.address {
+			t.Fatalf("hash moved from %v to %v", first.address, sock.address)
+		}
+	}
+	pool.MarkUnhealthy(first)
+	second, err := pool.Get("uid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.address == first.address {
+		t.Fatalf("unhealthy server %v still selected", first.address)
+	}
+	pool.MarkUnhealthy(second)
+	if _, err = pool.Get("uid"); err != ErrPoolUnavailable {
+		t.Fatalf("all servers unhealthy,error:%v", err)
+	}
+}
+
+func TestClientPoolRoundRobin(t *testing.T) {
+	_, a := testServer(t)
+	_, b := testServer(t)
+	pool := NewClientPool(New(), StaticProvider{a, b})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		sock, err := pool.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[sock.address]++
+	}
+	if seen[a] != 2 || seen[b] != 2 {
+		t.Fatalf("round robin %v", seen)
+	}
+}
+
+// 关闭连接池后连接由各自的读写协程销毁，不需要心跳，也不会重连
+func TestClientPoolClose(t *testing.T) {
+	_, a := testServer(t)
+	cl := New()
+	var reconnecting int32
+	cl.On(EventTypeReconnecting, func(*Socket, any) {
+		atomic.AddInt32(&reconnecting, 1)
+	})
+	pool := NewClientPool(cl, StaticProvider{a}, ClientPoolOptions{Size: 3})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	var sockets []*Socket
+	pool.Range(func(_ string, sock *Socket) bool {
+		sockets = append(sockets, sock)
+		return true
+	})
+	pool.Close()
+	testEventually(t, testTimeout, func() bool {
+		for _, sock := range sockets {
+			if sock.Status() != SocketStatusReleased {
+				return false
+			}
+		}
+		return true
+	})
+	if n := atomic.LoadInt32(&reconnecting); n != 0 {
+		t.Fatalf("closed pool sockets reconnecting:%d", n)
+	}
+}
@@ -0,0 +1,135 @@
+package cosnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hwcer/cosgo/scc"
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// Reconnect 客户端模式下断开当前连接并重新连接，可以指定新的服务器地址。
+// 正在重连时只更换地址，从下一次尝试开始生效。
+// 参数 address: 新的服务器地址，可选，为空时使用原地址。
+// 返回值: 不是客户端模式或者 Socket 已经销毁时返回错误。
+func (sock *Socket) Reconnect(address ...string) error {
+	if sock.Type() != listener.SocketTypeClient {
+		return errors.New("socket is not client")
+	}
+	if sock.Status() == SocketStatusReleased {
+		return errors.New("socket released")
+	}
+	if len(address) > 0 && address[0] != "" {
+		v := address[0]
+		sock.target.Store(&v)
+	}
+	// 关闭底层连接，由读协程断开并开始重连，正在重连时不影响
+	if sock.Status() == SocketStatusConnected {
+		sock.interrupt()
+	}
+	return nil
+}
+
+// dialAddress 客户端模式下重连使用的地址
+func (sock *Socket) dialAddress() string {
+	if v := sock.target.Load(); v != nil {
+		return *v
+	}
+	return sock.address
+}
+
+// tryReconnect 断线重连，仅仅作为客户端时自动重连服务器
+func (sock *Socket) tryReconnect() bool {
+	logger.Alert("socket reconnect:%s", sock.dialAddress())
+	scc.SGO(func(ctx context.Context) {
+		if err := sock.reconnect(ctx); err != nil {
+			sock.Emit(EventTypeReconnectFailed, err)
+			sock.release()
+		}
+	})
+	return false
+}
+
+// reconnect 按退避时间反复尝试重连，每次尝试前触发 EventTypeReconnecting 事件。
+// 返回值: 放弃重连时返回 *ReconnectError。
+func (sock *Socket) reconnect(ctx context.Context) error {
+	opts := sock.dialer
+	if opts == nil {
+		opts = &DialOptions{}
+	}
+	max := sock.sockets.Options.ClientReconnectMax
+	var attempt int32
+	for {
+		attempt++
+		sock.Emit(EventTypeReconnecting, attempt)
+		if opts.BeforeReconnect != nil {
+			if err := opts.BeforeReconnect(sock, attempt); err != nil {
+				return &ReconnectError{Attempt: attempt, Address: sock.dialAddress(), Err: err}
+			}
+		}
+		address := sock.dialAddress()
+		conn, err := sock.sockets.dial(address, opts)
+		if err == nil {
+			return sock.reconnected(conn, opts, attempt)
+		}
+		logger.Debug("try reconnect server[%d],error:%v", attempt, err)
+		if max > 0 && attempt >= max {
+			return &ReconnectError{Attempt: attempt, Address: address, Err: fmt.Errorf("max retry %d reached:%w", max, err)}
+		}
+		timer := time.NewTimer(sock.sockets.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &ReconnectError{Attempt: attempt, Address: address, Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
+
+// reconnected 重连成功后恢复连接，设置了 AfterReconnect 时先调用，再恢复发送断线期间积压的消息。
+func (sock *Socket) reconnected(conn listener.Conn, opts *DialOptions, attempt int32) error {
+	if opts.AfterReconnect == nil {
+		sock.connect(conn)
+		return nil
+	}
+	held := sock.hold()
+	sock.connect(conn)
+	if err := opts.AfterReconnect(sock); err != nil {
+		for _, m := range held {
+			message.Release(m)
+		}
+		sock.Emit(EventTypeReconnectFailed, &ReconnectError{Attempt: attempt, Address: sock.dialAddress(), Err: err})
+		sock.shutdown()
+		return nil
+	}
+	sock.resume(held)
+	return nil
+}
+
+// hold 取出写通道中等待发送的消息
+func (sock *Socket) hold() (r []message.Message) {
+	for {
+		select {
+		case m := <-sock.cwrite:
+			r = append(r, m)
+		default:
+			return
+		}
+	}
+}
+
+// resume 将 hold 取出的消息重新放入写通道，通道已满时丢弃并触发 EventTypeMessageDropped 事件
+func (sock *Socket) resume(held []message.Message) {
+	for _, m := range held {
+		select {
+		case sock.cwrite <- m:
+		default:
+			sock.Emit(EventTypeMessageDropped, messagePath(m))
+			message.Release(m)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package cosnet
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 客户端主动 Close 后不再断线重连
+func TestClientCloseNoReconnect(t *testing.T) {
+	_, address := testServer(t)
+	var reconnecting int32
+	released := make(chan struct{})
+	sock, _ := testClient(t, address, func(ss *Sockets) {
+		ss.On(EventTypeReconnecting, func(*Socket, any) {
+			atomic.AddInt32(&reconnecting, 1)
+		})
+		ss.On(EventTypeReleased, func(*Socket, any) {
+			close(released)
+		})
+	})
+	sock.Close()
+	sock.sockets.Heartbeat(1)
+	select {
+	case <-released:
+	case <-time.After(testTimeout):
+		t.Fatal("closed client not released")
+	}
+	if n := atomic.LoadInt32(&reconnecting); n != 0 || sock.Status() != SocketStatusReleased {
+		t.Fatalf("closed client reconnecting:%d,status:%d", n, sock.Status())
+	}
+}
+
+func TestReconnectHooks(t *testing.T) {
+	_, a := testServer(t)
+	b, address := testServer(t)
+	var before, after int32
+	opts := &DialOptions{
+		BeforeReconnect: func(sock *Socket, attempt int32) error {
+			atomic.AddInt32(&before, 1)
+			return sock.Reconnect(address)
+		},
+		AfterReconnect: func(sock *Socket) error {
+			atomic.AddInt32(&after, 1)
+			return nil
+		},
+	}
+	attempts := make(chan int32, 10)
+	sock, _ := testClientWithOptions(t, a, opts, func(ss *Sockets) {
+		ss.On(EventTypeReconnecting, func(_ *Socket, v any) {
+			attempts <- v.(int32)
+		})
+	})
+	if err := sock.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case n := <-attempts:
+		if n != 1 {
+			t.Fatalf("first attempt %d", n)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("EventTypeReconnecting not emitted")
+	}
+	// BeforeReconnect 更换地址后连接到服务器 b
+	testServerSocket(t, b)
+	testEventually(t, testTimeout, func() bool {
+		return sock.IsReady() && atomic.LoadInt32(&after) == 1
+	})
+	if n := atomic.LoadInt32(&before); n != 1 {
+		t.Fatalf("BeforeReconnect called %d times", n)
+	}
+}
+
+func TestReconnectFailed(t *testing.T) {
+	_, address := testServer(t)
+	failed := make(chan error, 1)
+	opts := &DialOptions{
+		BeforeReconnect: func(sock *Socket, attempt int32) error {
+			return errors.New("give up")
+		},
+	}
+	sock, _ := testClientWithOptions(t, address, opts, func(ss *Sockets) {
+		ss.On(EventTypeReconnectFailed, func(_ *Socket, v any) {
+			failed <- v.(error)
+		})
+	})
+	_ = sock.Reconnect()
+	select {
+	case err := <-failed:
+		var re *ReconnectError
+		if !errors.As(err, &re) || re.Attempt != 1 || re.Address != address {
+			t.Fatalf("reconnect error %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("EventTypeReconnectFailed not emitted")
+	}
+	testEventually(t, testTimeout, func() bool {
+		return sock.Status() == SocketStatusReleased
+	})
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	ss := New()
+	ss.Options.ClientReconnectTime = 100
+	ss.Options.ClientReconnectMaxDelay = 250
+	ss.Options.ClientReconnectJitter = 0
+	if d := ss.backoff(1); d != 100*time.Millisecond {
+		t.Fatalf("backoff(1) %v", d)
+	}
+	if d := ss.backoff(3); d != 250*time.Millisecond {
+		t.Fatalf("backoff(3) %v", d)
+	}
+	ss.Options.ClientReconnectJitter = 20
+	for i := 0; i < 100; i++ {
+		if d := ss.backoff(1); d < 80*time.Millisecond || d > 120*time.Millisecond {
+			t.Fatalf("backoff with jitter %v", d)
+		}
+	}
+}
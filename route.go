@@ -0,0 +1,40 @@
+package cosnet
+
+import (
+	"github.com/hwcer/cosgo/registry"
+)
+
+// Route 使用泛型在默认服务中注册强类型路由。
+// 请求体使用消息自身的 Binder 自动解析到 Req，返回的 Resp 作为确认包回传，
+// 解析失败时回传 ErrorCodeBadRequest，fn 返回 error 时经 ToError 转换后回传标准错误。注册时类型已确定，处理过程中不使用反射调用。
+// 参数:
+//   - ss: Sockets 管理器
+//   - path: 完整路由路径
+//   - fn: 处理函数
+//
+// 返回值: 错误信息
+func Route[Req any, Resp any](ss *Sockets, path string, fn func(*Context, *Req) (*Resp, error)) error {
+	return RouteWithService(ss.Service(""), path, fn)
+}
+
+// RouteWithService 使用泛型在指定服务中注册强类型路由，参考 Route。
+// 参数:
+//   - service: 服务实例，通过 Sockets.Service 获取
+//   - path: 路由路径，相对于服务
+//   - fn: 处理函数
+//
+// 返回值: 错误信息
+func RouteWithService[Req any, Resp any](service *registry.Service, path string, fn func(*Context, *Req) (*Resp, error)) error {
+	f := func(c *Context) any {
+		req := new(Req)
+		if err := c.Bind(req); err != nil {
+			return NewError(ErrorCodeBadRequest, err)
+		}
+		resp, err := fn(c, req)
+		if err != nil {
+			return err
+		}
+		return resp
+	}
+	return service.Register(f, path)
+}
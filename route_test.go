@@ -0,0 +1,47 @@
+package cosnet
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type testAddReq struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type testAddResp struct {
+	Sum int `json:"sum"`
+}
+
+func TestRoute(t *testing.T) {
+	_, address := testServer(t, func(ss *Sockets) {
+		err := Route(ss, "/add", func(c *Context, req *testAddReq) (*testAddResp, error) {
+			if req.A < 0 {
+				return nil, NewError(ErrorCodeBadRequest, "negative")
+			}
+			return &testAddResp{Sum: req.A + req.B}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	sock, replies := testClient(t, address)
+
+	_ = sock.Send(0, 1, "/add", &testAddReq{A: 1, B: 2})
+	r := testReceive(t, replies)
+	resp := &testAddResp{}
+	if err := json.Unmarshal(r.body, resp); err != nil || resp.Sum != 3 {
+		t.Fatalf("reply %s,err:%v", r.body, err)
+	}
+
+	_ = sock.Send(0, 2, "/add", []byte("{bad json"))
+	if e := testError(t, testReceive(t, replies)); e == nil || e.Code != ErrorCodeBadRequest {
+		t.Fatalf("bind error %+v", e)
+	}
+
+	_ = sock.Send(0, 3, "/add", &testAddReq{A: -1})
+	if e := testError(t, testReceive(t, replies)); e == nil || e.Code != ErrorCodeBadRequest || e.Message != "negative" {
+		t.Fatalf("handler error %+v", e)
+	}
+}
@@ -0,0 +1,137 @@
+package cosnet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+)
+
+// RTT 往返时延，由客户端主动心跳测量。
+type RTT struct {
+	Current  time.Duration `json:"current"`  // 最近一次测量值
+	Smoothed time.Duration `json:"smoothed"` // 平滑值，SRTT = 7/8*SRTT + 1/8*Current
+	Jitter   time.Duration `json:"jitter"`   // 抖动，RTTVAR = 3/4*RTTVAR + 1/4*|SRTT-Current|
+}
+
+// rttMeter 客户端心跳状态和 RTT 测量值。
+type rttMeter struct {
+	mutex sync.Mutex
+	index int32     // 心跳包序号
+	wait  int32     // 等待回应的心跳包序号，0 表示没有
+	sent  time.Time // 等待回应的心跳包发送时间
+	pong  time.Time // 最近一次收到心跳回应的时间
+	value RTT
+}
+
+// reset 连接建立时重置心跳状态，保留历史 RTT。
+func (r *rttMeter) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.wait = 0
+	r.pong = time.Now()
+}
+
+// update 使用新的样本更新 RTT。
+func (r *rttMeter) update(sample time.Duration) {
+	v := &r.value
+	if v.Smoothed == 0 {
+		v.Smoothed = sample
+		v.Jitter = sample / 2
+	} else {
+		diff := v.Smoothed - sample
+		if diff < 0 {
+			diff = -diff
+		}
+		v.Jitter = (3*v.Jitter + diff) / 4
+		v.Smoothed = (7*v.Smoothed + sample) / 8
+	}
+	v.Current = sample
+}
+
+// RTT 获取往返时延，仅客户端模式开启 ClientHeartbeat 后有效。
+func (sock *Socket) RTT() RTT {
+	sock.rtt.mutex.Lock()
+	defer sock.rtt.mutex.Unlock()
+	return sock.rtt.value
+}
+
+// ping 客户端模式的主动心跳协程，每 ClientHeartbeat 秒发送一次 FlagHeartbeat 心跳包。
+func (sock *Socket) ping(ctx context.Context) {
+	stop := sock.stop
+	ticker := time.NewTicker(time.Duration(sock.sockets.Options.ClientHeartbeat) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !sock.pingTrue() {
+				return
+			}
+		}
+	}
+}
+
+// pingTrue 发送心跳包，超过 ClientHeartbeatTimeout 未收到回应时断开连接。
+// 返回值: 是否继续心跳。
+func (sock *Socket) pingTrue() bool {
+	timeout := time.Duration(sock.sockets.Options.ClientHeartbeatTimeout) * time.Second
+	now := time.Now()
+	r := &sock.rtt
+	r.mutex.Lock()
+	elapsed := now.Sub(r.pong)
+	if timeout > 0 && elapsed > timeout {
+		r.mutex.Unlock()
+		sock.Emit(EventTypeHeartbeatTimeout, elapsed)
+		sock.disconnect()
+		return false
+	}
+	r.index++
+	if r.index <= 0 {
+		r.index = 1
+	}
+	r.wait = r.index
+	r.sent = now
+	index := r.index
+	r.mutex.Unlock()
+	if err := sock.Send(message.FlagHeartbeat, index, sock.sockets.Options.HeartbeatPath, []byte{}, false); err != nil {
+		sock.Errorf("client heartbeat error:%v", err)
+	}
+	return true
+}
+
+// pong 处理心跳回应并更新 RTT。
+// 返回值: 是否为等待中的心跳回应，不是时按普通消息处理。
+func (sock *Socket) pong(msg message.Message) bool {
+	if sock.Type() != listener.SocketTypeClient {
+		return false
+	}
+	r := &sock.rtt
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.wait == 0 || r.wait != msg.Index() {
+		return false
+	}
+	r.wait = 0
+	r.pong = time.Now()
+	r.update(r.pong.Sub(r.sent))
+	return true
+}
+
+// heartbeatReply 服务器自动回应未注册路由的心跳包。
+// 返回值: 是否为心跳包。
+func (sock *Socket) heartbeatReply(msg message.Message) bool {
+	flag := msg.Flag()
+	if !flag.Has(message.FlagHeartbeat) || flag.Has(message.FlagConfirm) {
+		return false
+	}
+	if !flag.Has(message.FlagNoreply) {
+		_ = sock.SendWithMagic(msg.Magic().Key, message.FlagConfirm|message.FlagHeartbeat, msg.Index(), msg.Confirm(), []byte{}, false)
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package cosnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientHeartbeatDisabledByDefault(t *testing.T) {
+	if v := New().Options; v.ClientHeartbeat != 0 || v.ClientHeartbeatTimeout != 0 {
+		t.Fatalf("client heartbeat enabled by default:%d,%d", v.ClientHeartbeat, v.ClientHeartbeatTimeout)
+	}
+}
+
+func TestRTT(t *testing.T) {
+	_, address := testServer(t)
+	sock, replies := testClient(t, address)
+	if !sock.pingTrue() {
+		t.Fatal("ping stopped")
+	}
+	testEventually(t, testTimeout, func() bool {
+		return sock.RTT().Current > 0
+	})
+	if r := sock.RTT(); r.Smoothed != r.Current {
+		t.Fatalf("first sample smoothed %v, current %v", r.Smoothed, r.Current)
+	}
+	// 心跳回应由 RTT 消费，不会作为普通消息抛出
+	select {
+	case r := <-replies:
+		t.Fatalf("heartbeat reply emitted as message %+v", r)
+	default:
+	}
+}
+
+func TestClientHeartbeatTimeout(t *testing.T) {
+	_, address := testServer(t)
+	timeout := make(chan time.Duration, 1)
+	sock, _ := testClient(t, address, func(ss *Sockets) {
+		ss.Options.ClientHeartbeatTimeout = 1
+		ss.On(EventTypeHeartbeatTimeout, func(_ *Socket, v any) {
+			timeout <- v.(time.Duration)
+		})
+	})
+	sock.rtt.mutex.Lock()
+	sock.rtt.pong = time.Now().Add(-2 * time.Second)
+	sock.rtt.mutex.Unlock()
+	if sock.pingTrue() {
+		t.Fatal("ping continued after heartbeat timeout")
+	}
+	select {
+	case d := <-timeout:
+		if d < 2*time.Second {
+			t.Fatalf("heartbeat timeout elapsed %v", d)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("EventTypeHeartbeatTimeout not emitted")
+	}
+}
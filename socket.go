@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/hwcer/cosgo/scc"
 	"github.com/hwcer/cosgo/session"
@@ -27,16 +30,42 @@ func (r *SocketAsyncResult) Wait() error {
 
 // Socket 表示一个网络连接，封装了底层的网络连接和会话数据。
 type Socket struct {
-	id        uint64               // 唯一标识符
-	conn      listener.Conn        // 底层网络连接
-	data      *session.Data        // 登录后绑定的用户会话数据
-	stop      chan struct{}        // 关闭信号通道
-	magic     byte                 // 消息魔数，用于消息格式识别
-	cwrite    chan message.Message // 写入通道，用于异步发送消息
-	status    int32                // 连接状态：0-正常，1-正在关闭，2-已关闭
-	sockets   *Sockets             // 所属的 Sockets 管理器
-	address   string               // 客户端模式：连接的服务器地址,为空时代表是服务器模式
-	heartbeat int32                // 心跳计数器
+	id         uint64                        // 唯一标识符
+	conn       atomic.Pointer[listener.Conn] // 底层网络连接，Socket 加入 Sockets 后才设置，Range 等可能并发读取
+	data       atomic.Pointer[session.Data]  // 登录后绑定的用户会话数据，可能在工作协程中设置
+	stop       chan struct{}                 // 关闭信号通道
+	ctx        context.Context               // 连接级上下文，断开连接时取消
+	cancel     context.CancelFunc            // 取消连接级上下文
+	magic      byte                          // 消息魔数，用于消息格式识别
+	cwrite     chan message.Message          // 写入通道，用于异步发送消息
+	chandle    chan message.Message          // 处理通道，仅 HandleModeSocket 模式使用
+	status     int32                         // 连接状态：0-正常，1-正在关闭，2-已关闭
+	sockets    *Sockets                      // 所属的 Sockets 管理器
+	address    string                        // 客户端模式：创建时连接的服务器地址,为空时代表是服务器模式
+	target     atomic.Pointer[string]        // 客户端模式：Reconnect 指定的新地址，nil 表示使用 address
+	heartbeat  int32                         // 心跳计数器
+	uptime     int32                         // 本次连接累计的心跳时长，单位秒
+	notfound   int32                         // 本次连接请求未知路由的次数
+	attributes attributes                    // 属性存储，Socket 销毁时清空
+	created    time.Time                     // 创建时间
+	rtt        rttMeter                      // 客户端主动心跳和 RTT
+	timeout    int32                         // 没有动作被判断为掉线的时间，单位秒，0 表示使用默认规则
+	listener   listener.Listener             // 服务器模式：接受该连接的监听器
+	dialer     *DialOptions                  // 客户端模式：连接选项，断线重连时使用
+	codec      *message.Codec                // 消息编解码配置，nil 表示使用全局配置
+	groups     sync.Map                      // 已加入的广播分组，string => struct{}
+	shard      uint64                        // HandleModeKeyed 模式下当前使用的分片键，仅读协程访问
+	queued     int32                         // 已放入共享工作池、尚未处理完的消息数量
+}
+
+// SocketNodeShift Socket ID 中节点编号的偏移量，高 16 位为 Config.NodeId，低 48 位为节点内自增序号。
+const SocketNodeShift = 48
+
+// SocketNodeId 从 Socket ID 中取出节点编号。
+// 参数 id: Socket ID。
+// 返回值: 创建该 Socket 的节点编号。
+func SocketNodeId(id uint64) uint16 {
+	return uint16(id >> SocketNodeShift)
 }
 
 // Socket 状态常量。
@@ -57,14 +86,30 @@ const (
 
 // connect 处理连接成功后的一些状态
 // 仅仅在Create 和 tryReconnect 中调用，可以安全的对 status 赋值
+// 读写协程只使用启动时的连接，断线重连后旧的协程不会访问新连接
 func (sock *Socket) connect(conn listener.Conn) {
-	sock.conn = conn
+	sock.conn.Store(&conn)
 	sock.stop = make(chan struct{})
-	sock.status = SocketStatusConnected
-	sock.heartbeat = 0
+	sock.ctx, sock.cancel = scc.WithCancel()
+	atomic.StoreInt32(&sock.heartbeat, 0)
+	atomic.StoreInt32(&sock.uptime, 0)
+	atomic.StoreInt32(&sock.notfound, 0)
+	atomic.StoreInt32(&sock.status, SocketStatusConnected)
 	sock.Emit(EventTypeConnected)
-	scc.SGO(sock.readMsg)
-	scc.SGO(sock.writeMsg)
+	stop := sock.stop
+	scc.SGO(func(ctx context.Context) {
+		sock.readMsg(ctx, conn, stop)
+	})
+	scc.SGO(func(ctx context.Context) {
+		sock.writeMsg(ctx, conn, stop)
+	})
+	if sock.chandle != nil {
+		scc.SGO(sock.handleMsg)
+	}
+	if sock.Type() == listener.SocketTypeClient && sock.sockets.Options.ClientHeartbeat > 0 {
+		sock.rtt.reset()
+		scc.SGO(sock.ping)
+	}
 }
 
 // isValidStatus 检查状态是否为活跃状态（可以执行操作的状态）
@@ -74,8 +119,9 @@ func isValidStatus(status int32) bool {
 
 // disconnect 断开连接时
 // 在工作协程和心跳中调用，仅仅当 SocketStatusConnected 时可以使用
+// 断开后保留已关闭的连接，RemoteAddr 等方法仍然可以使用
 func (sock *Socket) disconnect() bool {
-	status := sock.status
+	status := sock.Status()
 	if !isValidStatus(status) {
 		return false
 	}
@@ -88,30 +134,45 @@ func (sock *Socket) disconnect() bool {
 		}
 	}()
 	close(sock.stop)
-	if sock.conn != nil {
-		_ = sock.conn.Close()
-		sock.conn = nil
+	if sock.cancel != nil {
+		sock.cancel()
+	}
+	if conn := sock.Conn(); conn != nil {
+		_ = conn.Close()
 	}
 	sock.Emit(EventTypeDisconnect)
-	if sock.Type() == listener.SocketTypeClient {
-		sock.status = SocketStatusReconnecting
+	// 客户端主动调用 Close 关闭时不再重连
+	if sock.Type() == listener.SocketTypeClient && status != SocketStatusClosing {
+		atomic.StoreInt32(&sock.status, SocketStatusReconnecting)
 		return sock.tryReconnect()
 	}
-	sock.status = SocketStatusDisconnected
+	atomic.StoreInt32(&sock.status, SocketStatusDisconnected)
 	sock.release()
 	return true
 }
 
 // release 销毁socket
 func (sock *Socket) release() {
-	sock.status = SocketStatusReleased
+	atomic.StoreInt32(&sock.status, SocketStatusReleased)
 	atomic.AddInt64(&sock.sockets.count, -1)
 	sock.sockets.sockets.Delete(sock.id)
-	sock.data = nil
 	// 释放通道中的所有消息
+	drain(sock.cwrite)
+	drain(sock.chandle)
+	sock.Emit(EventTypeReleased)
+	sock.data.Store(nil)
+	sock.attributes.reset()
+	sock.groups.Clear()
+}
+
+// drain 释放通道中的所有消息
+func drain(c chan message.Message) {
+	if c == nil {
+		return
+	}
 	for {
 		select {
-		case msg, ok := <-sock.cwrite:
+		case msg, ok := <-c:
 			if !ok {
 				return
 			}
@@ -122,21 +183,6 @@ func (sock *Socket) release() {
 	}
 }
 
-// reconnect 断线重连，仅仅作为客户端时自动重连服务器
-func (sock *Socket) tryReconnect() bool {
-	address := sock.address
-	logger.Alert("socket reconnect:%s", address)
-	scc.SGO(func(ctx context.Context) {
-		if conn, err := sock.sockets.tryConnect(ctx, address, 0); err == nil {
-			sock.connect(conn)
-			return
-		}
-
-		sock.release()
-	})
-	return false
-}
-
 func (sock *Socket) Id() uint64 {
 	return sock.id
 }
@@ -148,14 +194,25 @@ func (sock *Socket) Is(target *Socket) bool {
 }
 
 func (sock *Socket) Data() *session.Data {
-	return sock.data
+	return sock.data.Load()
+}
+
+// Context 返回连接级 context.Context，断开连接时取消。
+func (sock *Socket) Context() context.Context {
+	if sock.ctx == nil {
+		return context.Background()
+	}
+	return sock.ctx
 }
 
 func (sock *Socket) Emit(e EventType, args ...any) {
 	sock.sockets.Emit(e, sock, args...)
 }
 func (sock *Socket) Conn() listener.Conn {
-	return sock.conn
+	if c := sock.conn.Load(); c != nil {
+		return *c
+	}
+	return nil
 }
 func (sock *Socket) Type() listener.SocketType {
 	if sock.address != "" {
@@ -174,9 +231,24 @@ func (sock *Socket) Close(delay ...int32) {
 	if !atomic.CompareAndSwapInt32(&sock.status, SocketStatusConnected, SocketStatusClosing) {
 		return
 	}
-	sock.heartbeat = Options.SocketConnectTime
+	heartbeat := sock.Timeout()
 	if len(delay) > 0 {
-		sock.heartbeat -= delay[0]
+		heartbeat -= delay[0]
+	}
+	atomic.StoreInt32(&sock.heartbeat, heartbeat)
+}
+
+// shutdown 立即关闭连接且不再重连，可以在任意协程中调用。
+// 先标记为 SocketStatusClosing，再关闭底层连接，由读写协程退出时调用 disconnect 完成销毁。
+func (sock *Socket) shutdown() {
+	sock.Close()
+	sock.interrupt()
+}
+
+// interrupt 关闭底层连接，读协程随之退出并调用 disconnect，可以在任意协程中调用。
+func (sock *Socket) interrupt() {
+	if conn := sock.Conn(); conn != nil {
+		_ = conn.Close()
 	}
 }
 
@@ -185,7 +257,7 @@ func (sock *Socket) Close(delay ...int32) {
 //   - v: 用户会话数据
 //   - reconnect: 是否为重连，可选
 func (sock *Socket) Authentication(v *session.Data, reconnect ...bool) {
-	sock.data = v
+	sock.data.Store(v)
 	var r bool
 	if len(reconnect) > 0 {
 		r = reconnect[0]
@@ -200,7 +272,7 @@ func (sock *Socket) Authentication(v *session.Data, reconnect ...bool) {
 // 参数 ip: 新登录的 IP 地址。
 func (sock *Socket) Replaced(ip string) {
 	sock.Emit(EventTypeReplaced, ip)
-	sock.data = nil // 取消与角色关联，避免触发角色的掉线事件
+	sock.data.Store(nil) // 取消与角色关联，避免触发角色的掉线事件
 	sock.Close(Options.SocketReplacedTime)
 }
 
@@ -211,23 +283,23 @@ func (sock *Socket) Errorf(format any, args ...any) {
 // KeepAlive 重置心跳计数器，表示连接活跃。
 // 仅在 SocketStatusNone 或 SocketStatusConnected 状态下有效。
 func (sock *Socket) KeepAlive() {
-	if sock.status == SocketStatusConnected {
-		sock.heartbeat = 0
+	if sock.Status() == SocketStatusConnected {
+		atomic.StoreInt32(&sock.heartbeat, 0)
 	}
-	if sock.data != nil {
-		sock.data.KeepAlive()
+	if data := sock.data.Load(); data != nil {
+		data.KeepAlive()
 	}
 }
 
 func (sock *Socket) LocalAddr() net.Addr {
-	if sock.conn != nil {
-		return sock.conn.LocalAddr()
+	if conn := sock.Conn(); conn != nil {
+		return conn.LocalAddr()
 	}
 	return nil
 }
 func (sock *Socket) RemoteAddr() net.Addr {
-	if sock.conn != nil {
-		return sock.conn.RemoteAddr()
+	if conn := sock.Conn(); conn != nil {
+		return conn.RemoteAddr()
 	}
 	return nil
 }
@@ -243,13 +315,13 @@ func (sock *Socket) Magic(magic ...byte) byte {
 func (sock *Socket) Send(flag message.Flag, index int32, path any, data any, safe ...bool) error {
 	magic := sock.magic
 	if magic == 0 {
-		magic = message.Options.Magic
+		magic = sock.codec.DefaultMagic()
 	}
 	return sock.SendWithMagic(magic, flag, index, path, data, safe...)
 }
 
 func (sock *Socket) SendWithMagic(magic byte, flag message.Flag, index int32, path any, data any, safe ...bool) error {
-	m := message.Require()
+	m := message.Require(sock.codec)
 	if err := m.Marshal(magic, flag, index, path, data); err != nil {
 		message.Release(m)
 		return fmt.Errorf("socket send marshal error: %w", err)
@@ -295,7 +367,7 @@ func (sock *Socket) Write(m message.Message, safe ...bool) (err error) {
 		}
 	}()
 	if !sock.IsReady() {
-		return fmt.Errorf("socket not ready, status: %d", sock.status)
+		return fmt.Errorf("socket not ready, status: %d", sock.Status())
 	}
 	// safe 模式（默认）：阻塞等待通道可用，但监听 stop 避免 socket 关闭后永久阻塞
 	// 非 safe 模式：通道满时直接丢弃
@@ -305,6 +377,7 @@ func (sock *Socket) Write(m message.Message, safe ...bool) (err error) {
 		case <-sock.stop:
 			return fmt.Errorf("socket closed")
 		default:
+			sock.Emit(EventTypeMessageDropped, messagePath(m))
 			return fmt.Errorf("socket write channel full")
 		}
 	} else {
@@ -317,17 +390,33 @@ func (sock *Socket) Write(m message.Message, safe ...bool) (err error) {
 	return nil
 }
 
+// Status 获取 Socket 状态，参考 SocketStatus 常量。
+func (sock *Socket) Status() int32 {
+	return atomic.LoadInt32(&sock.status)
+}
+
+// Created 获取 Socket 创建时间。
+func (sock *Socket) Created() time.Time {
+	return sock.created
+}
+
+// Pending 获取发送通道中等待发送的消息数量。
+func (sock *Socket) Pending() int {
+	return len(sock.cwrite)
+}
+
 // IsReady 检查 Socket 是否处于可读写状态。
 // 返回值: 如果 Socket 状态正常且已连接则返回 true。
 func (sock *Socket) IsReady() bool {
-	return sock.status == SocketStatusConnected
+	return sock.Status() == SocketStatusConnected
 }
 
-func (sock *Socket) readMsg(_ context.Context) {
-	defer sock.disconnect()
+// readMsg 读协程，conn 和 stop 为启动时的连接和关闭信号
+func (sock *Socket) readMsg(_ context.Context, conn listener.Conn, stop chan struct{}) {
+	defer sock.disconnectConn(stop)
 	for !scc.Stopped() {
-		msg := message.Require()
-		if err := sock.conn.ReadMessage(sock, msg); err != nil {
+		msg := message.Require(sock.codec)
+		if err := conn.ReadMessage(sock, msg); err != nil {
 			message.Release(msg)
 			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
 				sock.Errorf(err)
@@ -335,34 +424,69 @@ func (sock *Socket) readMsg(_ context.Context) {
 			return
 		}
 		sock.readMsgTrue(msg)
-		message.Release(msg)
 	}
 }
 
+// readMsgTrue 处理读取到的消息，消息由 dispatch 负责回收
 func (sock *Socket) readMsgTrue(msg message.Message) {
 	sock.KeepAlive()
 	magic := msg.Magic()
 	if magic == nil || magic.Key == 0 {
 		logger.Debug("magic is nil :%v", msg)
+		message.Release(msg)
 		return //未被初始化的消息
 	}
-	sock.handle(sock, msg)
+	if flag := msg.Flag(); flag.Has(message.FlagConfirm) && flag.Has(message.FlagHeartbeat) && sock.pong(msg) {
+		message.Release(msg)
+		return
+	}
+	sock.dispatch(msg)
 }
 
 func (sock *Socket) handle(socket *Socket, msg message.Message) {
 	defer func() {
 		if e := recover(); e != nil {
 			socket.Errorf("server handle error:%v", e)
+			_ = socket.replyError(msg, NewError(ErrorCodeInternal, "internal server error"))
 		}
 	}()
+	for _, interceptor := range sock.sockets.interceptors {
+		if interceptor(socket, msg) {
+			return
+		}
+	}
+	sock.serve(socket, msg, nil, nil)
+}
+
+// Serve 按正常流程（路由、身份认证、中间件、回复）处理一条消息，不经过拦截器，用于网关转发等场景。
+// 参数:
+//   - msg: 要处理的消息，调用方负责回收
+//   - data: 本次请求使用的会话数据，通过 Context.Data 获取，nil 表示使用 Socket 的会话数据
+//   - values: 请求级数据，通过 Context.Value 获取，可以为 nil
+func (sock *Socket) Serve(msg message.Message, data *session.Data, values map[any]any) {
+	defer func() {
+		if e := recover(); e != nil {
+			sock.Errorf("server handle error:%v", e)
+			_ = sock.replyError(msg, NewError(ErrorCodeInternal, "internal server error"))
+		}
+	}()
+	sock.serve(sock, msg, data, values)
+}
+
+func (sock *Socket) serve(socket *Socket, msg message.Message, data *session.Data, values map[any]any) {
 	path, _, err := msg.Path()
 	if err != nil {
 		socket.Errorf("message path error code:%d error:%v", msg.Code(), err)
+		_ = socket.replyError(msg, NewError(ErrorCodeBadRequest, err))
 		return
 	}
 	node, _ := sock.sockets.Registry.Search(RegistryMethod, path)
 	if node == nil {
+		if socket.heartbeatReply(msg) {
+			return
+		}
 		socket.Emit(EventTypeMessage, msg)
+		socket.notFound(msg, path)
 		return
 	}
 	handler := node.Handler().(*Handler)
@@ -370,35 +494,118 @@ func (sock *Socket) handle(socket *Socket, msg message.Message) {
 		socket.Errorf("no handler for %s", path)
 		return
 	}
-	c := &Context{Socket: socket, Message: msg}
-	reply := handler.handle(node, c)
+	c := newContext(socket, msg, handler.timeout(node))
+	defer c.release()
+	c.data = data
+	for k, v := range values {
+		c.WithValue(k, v)
+	}
+	if c.Data() == nil && handler.authentication(node, sock.sockets.Options.AuthenticationRequired) {
+		socket.Emit(EventTypeUnauthorized, path)
+		if err = socket.replyError(msg, ErrAuthenticationRequired); err != nil {
+			socket.Errorf("write reply message error,path:%s,errMsg:%v", path, err)
+		}
+		return
+	}
+	reply := handler.serve(node, c, sock.sockets.middleware)
 	if err = handler.reply(c, reply); err != nil {
 		socket.Errorf("write reply message error,path:%s,errMsg:%v", path, err)
 	}
 }
 
-func (sock *Socket) writeMsg(ctx context.Context) {
-	defer sock.disconnect()
+// notFound 按 Options.NotFoundPolicy 处理未知路由，仅作用于服务器模式。
+func (sock *Socket) notFound(msg message.Message, path string) {
+	policy := sock.sockets.Options.NotFoundPolicy
+	if policy == NotFoundPolicyEvent || sock.Type() != listener.SocketTypeServer || msg.Flag().Has(message.FlagConfirm) {
+		return
+	}
+	if err := sock.replyError(msg, ErrRouteNotFound); err != nil {
+		sock.Errorf("write reply message error,path:%s,errMsg:%v", path, err)
+	}
+	if policy != NotFoundPolicyClose {
+		return
+	}
+	if n := atomic.AddInt32(&sock.notfound, 1); n >= sock.sockets.Options.NotFoundMaxCount {
+		sock.Errorf("too many unknown routes:%d,last path:%s", n, path)
+		sock.Close()
+	}
+}
+
+// messagePath 获取消息路径用于事件和日志，无法解析时返回协议号。
+func messagePath(m message.Message) string {
+	path, _, err := m.Path()
+	if err != nil {
+		path = strconv.Itoa(int(m.Code()))
+	}
+	return path
+}
+
+// ReplyError 回复标准错误包，msg 为确认包或 FlagNoreply 消息时不回复，用于拦截器等场景。
+// 参数:
+//   - msg: 需要回复的消息
+//   - err: 错误信息，经 ToError 转换
+//
+// 返回值: 发送失败时返回错误
+func (sock *Socket) ReplyError(msg message.Message, err error) error {
+	return sock.replyError(msg, err)
+}
+
+// replyError 回复标准错误包，确认包本身和明确不需要回复的请求不回复。
+// 参数:
+//   - msg: 请求消息
+//   - err: 错误信息，使用 ToError 转换为标准错误
+//   - safe: 可选，参考 Write
+//
+// 返回值: 错误信息。
+func (sock *Socket) replyError(msg message.Message, err error, safe ...bool) error {
+	flag := msg.Flag()
+	if flag.Has(message.FlagConfirm) || flag.Has(message.FlagNoreply) {
+		return nil
+	}
+	replyFlag := message.FlagConfirm | message.FlagError
+	if flag.Has(message.FlagHeartbeat) {
+		replyFlag.Set(message.FlagHeartbeat)
+	}
+	data, e := ErrorBinder.Marshal(ToError(err))
+	if e != nil {
+		return e
+	}
+	return sock.SendWithMagic(msg.Magic().Key, replyFlag, msg.Index(), msg.Confirm(), data, safe...)
+}
+
+// writeMsg 写协程，conn 和 stop 为启动时的连接和关闭信号
+func (sock *Socket) writeMsg(ctx context.Context, conn listener.Conn, stop chan struct{}) {
+	defer sock.disconnectConn(stop)
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-sock.stop:
+		case <-stop:
 			return
 		case msg := <-sock.cwrite:
-			sock.writeMsgTrue(msg)
+			sock.writeMsgTrue(conn, msg)
 		}
 	}
 }
 
-func (sock *Socket) writeMsgTrue(msg message.Message) {
+// disconnectConn 读写协程退出时断开连接，stop 已经关闭说明该连接已经断开，
+// 此时 Socket 可能已经重连成功，不能再断开
+func (sock *Socket) disconnectConn(stop chan struct{}) {
+	select {
+	case <-stop:
+	default:
+		sock.disconnect()
+	}
+}
+
+func (sock *Socket) writeMsgTrue(conn listener.Conn, msg message.Message) {
 	defer func() {
 		if e := recover(); e != nil {
 			sock.Errorf(e)
 		}
 		message.Release(msg)
 	}()
-	if err := sock.conn.WriteMessage(sock, msg); err != nil {
+	if err := conn.WriteMessage(sock, msg); err != nil {
 		sock.Errorf(err)
 	}
 }
@@ -408,15 +615,27 @@ func (sock *Socket) writeMsgTrue(msg message.Message) {
 // 返回值: 当前心跳计数。
 func (sock *Socket) Heartbeat(v int32) int32 {
 	// 如果设置了连接超时时间，并且心跳计数超过了超时时间，则断开连接
-	status := sock.status
-	if !isValidStatus(status) {
-		return sock.heartbeat
+	if !isValidStatus(sock.Status()) {
+		return atomic.LoadInt32(&sock.heartbeat)
 	}
-	sock.heartbeat += v
-	if Options.SocketConnectTime > 0 && sock.heartbeat > Options.SocketConnectTime {
+	heartbeat := atomic.AddInt32(&sock.heartbeat, v)
+	atomic.AddInt32(&sock.uptime, v)
+	if limit := sock.Timeout(); limit > 0 && heartbeat > limit {
+		sock.disconnect()
+	} else if sock.authenticationTimeout() {
+		sock.Emit(EventTypeUnauthorized)
 		sock.disconnect()
 	} else {
 		sock.Emit(EventTypeHeartbeat, v)
 	}
-	return sock.heartbeat
+	return heartbeat
+}
+
+// authenticationTimeout 检查服务器模式下的连接是否超过了身份认证宽限期。
+func (sock *Socket) authenticationTimeout() bool {
+	limit := sock.sockets.Options.AuthenticationTimeout
+	if limit <= 0 || sock.data.Load() != nil || sock.Status() != SocketStatusConnected {
+		return false
+	}
+	return sock.Type() == listener.SocketTypeServer && atomic.LoadInt32(&sock.uptime) > limit
 }
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -29,9 +30,16 @@ import (
 // 参数 r: 输入流读取器。
 // 返回值: 是否包含有效的消息魔术数字。
 func Matcher(r io.Reader) bool {
+	return Default.Matcher(r)
+}
+
+// Matcher 按 Options.Codec 检查输入流是否包含有效的消息魔术数字，可以作为 mux.Matcher 在端口复用时识别原生客户端。
+// 参数 r: 输入流读取器。
+// 返回值: 是否包含有效的消息魔术数字。
+func (ss *Sockets) Matcher(r io.Reader) bool {
 	buf := make([]byte, 1)
 	n, _ := r.Read(buf)
-	return n == 1 && message.Magics.Has(buf[0])
+	return n == 1 && ss.Options.Codec.HasMagic(buf[0])
 }
 
 // New 创建一个新的 Sockets 管理器。
@@ -40,7 +48,6 @@ func New() *Sockets {
 	ss := &Sockets{
 		index:    0,
 		sockets:  syncmap.Map{},
-		emitter:  make(map[EventType][]EventsFunc),
 		instance: make([]listener.Listener, 0),
 		Options:  Options,
 		Registry: registry.New(),
@@ -50,14 +57,25 @@ func New() *Sockets {
 
 // Sockets 管理 Socket 连接的集合，包含服务器和客户端功能。
 type Sockets struct {
-	index    uint64                     // Socket 索引计数器
-	count    int64                      // 当前连接数
-	started  atomic.Bool                // 是否已启动
-	sockets  syncmap.Map                // 存储所有 Socket 连接
-	emitter  map[EventType][]EventsFunc // 事件监听器映射
-	instance []listener.Listener        // 监听器实例列表
-	Options  Config                     // 配置选项
-	Registry *registry.Registry         // 消息处理器注册器
+	index        uint64              // Socket 索引计数器
+	count        int64               // 当前连接数
+	started      atomic.Bool         // 是否已启动
+	sockets      syncmap.Map         // 存储所有 Socket 连接
+	emitter      emitter             // 事件监听器集合
+	instance     []listener.Listener // 监听器实例列表
+	timeouts     syncmap.Map         // 监听器的掉线超时时间，listener.Listener => int32
+	codecs       syncmap.Map         // 监听器的消息编解码配置，listener.Listener => *message.Codec
+	middleware   []HandlerMiddleware // 全局中间件
+	interceptors []Interceptor       // 消息拦截器，路由之前调用
+	acceptHooks  []AcceptHook        // 准入检查，创建 Socket 之前调用
+	rejected     int64               // 被准入检查拒绝的连接数
+	rejectLog    int64               // 上一次记录拒绝日志的时间，UnixNano
+	rejectSkip   int64               // 上一次记录日志之后未记录的拒绝次数
+	dispatch     *dispatcher         // 共享工作池，HandleModePool 和 HandleModeKeyed 模式使用
+	dispatchOnce sync.Once
+	bus          atomic.Pointer[busBinding] // 跨节点广播总线
+	Options      Config                     // 配置选项
+	Registry     *registry.Registry         // 消息处理器注册器
 }
 
 // Create 创建新 Socket 并自动加入到 Sockets 管理器。
@@ -66,6 +84,16 @@ type Sockets struct {
 //   - socket: 创建的 Socket 实例
 //   - err: 错误信息
 func (ss *Sockets) Create(conn listener.Conn) (socket *Socket, err error) {
+	return ss.create(conn, "", nil, nil)
+}
+
+// create 创建 Socket。
+// 参数:
+//   - conn: 底层网络连接
+//   - address: 服务器地址，不为空时为客户端模式
+//   - ln: 服务器模式下接受该连接的监听器
+//   - dialer: 客户端模式下的连接选项，断线重连时使用
+func (ss *Sockets) create(conn listener.Conn, address string, ln listener.Listener, dialer *DialOptions) (socket *Socket, err error) {
 	if scc.Stopped() {
 		return nil, errors.New("server closed")
 	}
@@ -78,10 +106,15 @@ func (ss *Sockets) Create(conn listener.Conn) (socket *Socket, err error) {
 		}
 	}
 
-	socket = &Socket{sockets: ss}
-	socket.id = atomic.AddUint64(&ss.index, 1)
+	socket = &Socket{sockets: ss, address: address, listener: ln, dialer: dialer}
+	socket.codec = ss.listenerCodec(ln)
+	socket.id = uint64(ss.Options.NodeId)<<SocketNodeShift | atomic.AddUint64(&ss.index, 1)
 	socket.cwrite = make(chan message.Message, ss.Options.WriteChanSize)
+	if ss.Options.HandleMode == HandleModeSocket {
+		socket.chandle = make(chan message.Message, ss.Options.HandleQueueSize)
+	}
 	socket.status = SocketStatusNone
+	socket.created = time.Now()
 	ss.sockets.Store(socket.id, socket)
 	atomic.AddInt64(&ss.count, 1)
 	socket.connect(conn)
@@ -109,6 +142,22 @@ func (ss *Sockets) Range(fn func(socket *Socket) bool) {
 	})
 }
 
+// Count 获取当前连接数。
+func (ss *Sockets) Count() int64 {
+	return atomic.LoadInt64(&ss.count)
+}
+
+// Listeners 获取所有监听器。
+func (ss *Sockets) Listeners() []listener.Listener {
+	return append([]listener.Listener(nil), ss.instance...)
+}
+
+// Subscribers 获取事件的监听函数数量。
+// 参数 e: 事件类型。
+func (ss *Sockets) Subscribers(e EventType) int {
+	return ss.emitter.count(e)
+}
+
 // Service 创建或获取服务。
 // 参数 name: 服务名称，为空则创建默认服务。
 // 返回值: 服务实例。
@@ -147,14 +196,48 @@ func (ss *Sockets) Register(i interface{}, prefix ...string) error {
 	return service.Register(i, prefix...)
 }
 
+// Use 添加全局中间件，作用于所有服务（初始化时使用）。
+// 按添加顺序由外到内执行，位于各服务 Handler.Use 注册的中间件之外。
+// 参数 middleware: 中间件列表。
+func (ss *Sockets) Use(middleware ...HandlerMiddleware) {
+	ss.middleware = append(ss.middleware, middleware...)
+}
+
+// Intercept 添加消息拦截器（初始化时使用），在路由之前按添加顺序调用，用于网关转发等场景。
+// 参数 interceptor: 拦截器列表。
+func (ss *Sockets) Intercept(interceptor ...Interceptor) {
+	ss.interceptors = append(ss.interceptors, interceptor...)
+}
 
+// On 注册同步事件处理函数，可以在任意时刻调用。
+// 处理函数在触发事件的协程中执行，panic 会被隔离，不会影响连接。
+// 参数:
+//   - e: 事件类型
+//   - f: 事件处理函数
+//
+// 返回值: 监听句柄，调用 Off 取消监听
+func (ss *Sockets) On(e EventType, f EventsFunc) *Subscription {
+	return ss.emitter.add(e, f, 0)
+}
 
-// On 注册事件处理函数（初始化时使用）。
+// OnAsync 注册异步事件处理函数，可以在任意时刻调用。
+// 每个监听器拥有独立的队列和协程，按事件触发顺序依次执行，队列已满时丢弃事件。
+// 事件附加数据为 message.Message 时（如 EventTypeMessage）投递的是副本，原消息处理完毕后会被回收。
 // 参数:
 //   - e: 事件类型
 //   - f: 事件处理函数
-func (ss *Sockets) On(e EventType, f EventsFunc) {
-	ss.emitter[e] = append(ss.emitter[e], f)
+//   - queue: 可选，队列长度，默认 Options.EventQueueSize
+//
+// 返回值: 监听句柄，调用 Off 取消监听
+func (ss *Sockets) OnAsync(e EventType, f EventsFunc, queue ...int32) *Subscription {
+	size := ss.Options.EventQueueSize
+	if len(queue) > 0 {
+		size = queue[0]
+	}
+	if size <= 0 {
+		size = 1
+	}
+	return ss.emitter.add(e, f, size)
 }
 
 // Emit 触发事件。
@@ -167,8 +250,8 @@ func (ss *Sockets) Emit(e EventType, s *Socket, attach ...any) {
 	if len(attach) > 0 {
 		v = attach[0]
 	}
-	for _, f := range ss.emitter[e] {
-		f(s, v)
+	for _, sub := range ss.emitter.get(e) {
+		sub.push(s, v)
 	}
 }
 
@@ -194,13 +277,20 @@ func (ss *Sockets) Errorf(s *Socket, format any, args ...any) {
 
 // Listen 监听指定地址。
 // 参数:
-//   - address: 监听地址
-//   - tlsConfig: TLS 配置，仅用于 WebSocket，可选
+//   - address: 监听地址，例如 tcp://:3000、tls://:3443、ws://:8080、wss://:8443、udp://:3001、
+//     unix:///tmp/cosnet.sock、unix://@cosnet（Linux 抽象命名空间）
+//   - tlsConfig: TLS 配置，用于 wss 和 tls（tcps），可选
 //
 // 返回值:
 //   - listener: 监听器实例
 //   - err: 错误信息
 func (ss *Sockets) Listen(address string, tlsConfig ...*tls.Config) (listener listener.Listener, err error) {
+	if i := strings.Index(address, "://"); i >= 0 && strings.ToLower(address[:i]) == "unix" {
+		if listener, err = tcp.NewUnix(address[i+3:], ss.Options.TCP); err == nil {
+			ss.Accept(listener)
+		}
+		return
+	}
 	addr := utils.NewAddress(address)
 	if addr.Scheme == "" {
 		addr.Scheme = "tcp"
@@ -208,11 +298,16 @@ func (ss *Sockets) Listen(address string, tlsConfig ...*tls.Config) (listener li
 	network := strings.ToLower(addr.Scheme)
 	switch network {
 	case "tcp", "tcp4", "tcp6":
-		listener, err = tcp.New(network, addr.String())
+		listener, err = tcp.NewWithConfig(network, addr.String(), ss.Options.TCP)
+	case "tls", "tls4", "tls6", "tcps", "tcps4", "tcps6":
+		if len(tlsConfig) == 0 || tlsConfig[0] == nil {
+			return nil, errors.New("TLS configuration is required for tls network type")
+		}
+		listener, err = tcp.NewTLS(tlsNetwork(network), addr.String(), tlsConfig[0], ss.Options.TCP)
 	case "ws", "wss", "wss4", "wss5", "wss6":
-		listener, err = wss.New(network, addr.String(), tlsConfig...)
+		listener, err = wss.NewWithConfig(network, addr.String(), ss.Options.WSS, tlsConfig...)
 	case "udp", "udp4", "udp6":
-		listener, err = udp.New(network, addr.String())
+		listener, err = udp.NewWithConfig(network, addr.String(), ss.Options.UDP)
 	default:
 		err = errors.New("address scheme unknown")
 	}
@@ -226,6 +321,7 @@ func (ss *Sockets) Listen(address string, tlsConfig ...*tls.Config) (listener li
 // Accept 接受监听器的连接请求。
 // 参数 ln: 监听器实例。
 func (ss *Sockets) Accept(ln listener.Listener) {
+	ss.instance = append(ss.instance, ln)
 	scc.CGO(func(ctx context.Context) {
 		defer func() {
 			if err := recover(); err != nil {
@@ -235,11 +331,17 @@ func (ss *Sockets) Accept(ln listener.Listener) {
 		defer func() {
 			_ = ln.Close()
 		}()
-		ss.instance = append(ss.instance, ln)
 		for !scc.Stopped() {
 			conn, err := ln.Accept()
+			if err == nil && len(ss.acceptHooks) > 0 {
+				// 准入检查和拒绝包可能较慢，在独立协程中执行，不阻塞接受其它连接
+				scc.SGO(func(_ context.Context) {
+					ss.admitAndCreate(conn, ln)
+				})
+				continue
+			}
 			if err == nil {
-				_, err = ss.Create(conn)
+				_, err = ss.create(conn, "", ln, nil)
 			}
 			if errors.Is(err, net.ErrClosed) {
 				return
@@ -295,67 +397,59 @@ func (ss *Sockets) Address() string {
 }
 
 // Connect 连接服务器。
-// 参数 address: 服务器地址。
+// 参数:
+//   - address: 服务器地址，scheme 支持 tcp、ws、wss、udp、unix，例如 ws://127.0.0.1:8080/ws
+//   - opts: 连接选项，可选，断线重连时继续使用
+//
 // 返回值:
 //   - socket: 连接的 Socket 实例
 //   - err: 错误信息
-func (ss *Sockets) Connect(address string) (socket *Socket, err error) {
+func (ss *Sockets) Connect(address string, opts ...*DialOptions) (socket *Socket, err error) {
+	var dialer *DialOptions
+	if len(opts) > 0 {
+		dialer = opts[0]
+	}
 	ctx, cancel := scc.WithCancel()
 	defer cancel()
-	conn, err := ss.tryConnect(ctx, address, 0)
+	conn, err := ss.tryConnect(ctx, address, dialer, 0)
 	if err != nil {
 		return nil, err
 	}
-	socket, err = ss.Create(conn)
-	if err == nil {
-		socket.address = address
-	}
-	return
+	return ss.create(conn, address, nil, dialer)
 }
 
 // tryConnect 尝试连接服务器，支持指数退避和 context 取消。
 // 参数:
 //   - ctx: 上下文，用于控制退出
 //   - s: 服务器地址
+//   - opts: 连接选项
 //   - tryCount: 当前重试次数
 //
 // 返回值:
 //   - conn: 连接对象
 //   - err: 错误信息
-func (ss *Sockets) tryConnect(ctx context.Context, s string, tryCount int32) (listener.Conn, error) {
-	address := utils.NewAddress(s)
-	if address.Scheme == "" {
-		address.Scheme = "tcp"
-	}
-	rs := address.String()
-
+func (ss *Sockets) tryConnect(ctx context.Context, s string, opts *DialOptions, tryCount int32) (listener.Conn, error) {
 	// 检查最大重试次数
 	if ss.Options.ClientReconnectMax > 0 && tryCount >= ss.Options.ClientReconnectMax {
 		return nil, fmt.Errorf("max retry %d reached", ss.Options.ClientReconnectMax)
 	}
 
-	conn, err := net.DialTimeout(address.Scheme, rs, time.Second)
+	conn, err := ss.dial(s, opts)
 	if err == nil {
-		return tcp.NewConn(conn), nil
+		return conn, nil
 	}
 
 	logger.Debug("try connect server[%d],error:%v", tryCount, err)
 
-	// 指数退避计算
-	delay := ss.Options.ClientReconnectTime * (tryCount + 1)
-	if ss.Options.ClientReconnectMaxDelay > 0 && delay > ss.Options.ClientReconnectMaxDelay {
-		delay = ss.Options.ClientReconnectMaxDelay
-	}
-
 	// 使用定时器等待，支持 context 取消
-	timer := time.NewTimer(time.Duration(delay) * time.Millisecond)
+	timer := time.NewTimer(ss.backoff(tryCount + 1))
 	defer timer.Stop()
 
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	case <-timer.C:
-		return ss.tryConnect(ctx, s, tryCount+1)
+		return ss.tryConnect(ctx, s, opts, tryCount+1)
 	}
 }
 
@@ -0,0 +1,109 @@
+package cosnet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/cosnet/tcp"
+)
+
+// testTimeout 测试中等待结果的最长时间
+const testTimeout = 2 * time.Second
+
+// testReply 客户端收到的消息，消息会被回收，需要复制
+type testReply struct {
+	flag  message.Flag
+	index int32
+	path  string
+	body  []byte
+}
+
+// testServer 创建监听随机端口的服务器
+// 参数 setup: 监听之前修改配置、注册路由
+// 返回值: 服务器和连接地址
+func testServer(t *testing.T, setup ...func(ss *Sockets)) (*Sockets, string) {
+	t.Helper()
+	ss := New()
+	for _, f := range setup {
+		f(ss)
+	}
+	ln, err := tcp.New("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.Accept(ln)
+	t.Cleanup(func() {
+		_ = ln.Close()
+		ss.Range(func(sock *Socket) bool {
+			sock.Close()
+			return true
+		})
+	})
+	return ss, "tcp://" + ln.Addr().String()
+}
+
+// testClient 连接服务器，返回客户端 Socket 和收到的消息
+func testClient(t *testing.T, address string, setup ...func(ss *Sockets)) (*Socket, <-chan *testReply) {
+	t.Helper()
+	return testClientWithOptions(t, address, nil, setup...)
+}
+
+// testClientWithOptions 使用指定的连接选项连接服务器，参考 testClient
+func testClientWithOptions(t *testing.T, address string, opts *DialOptions, setup ...func(ss *Sockets)) (*Socket, <-chan *testReply) {
+	t.Helper()
+	cl := New()
+	for _, f := range setup {
+		f(cl)
+	}
+	replies := make(chan *testReply, 100)
+	cl.On(EventTypeMessage, func(_ *Socket, v any) {
+		m := v.(message.Message)
+		replies <- &testReply{flag: m.Flag(), index: m.Index(), path: messagePath(m), body: append([]byte(nil), m.Body()...)}
+	})
+	sock, err := cl.Connect(address, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sock.Close()
+	})
+	return sock, replies
+}
+
+// testReceive 等待客户端收到下一条消息
+func testReceive(t *testing.T, replies <-chan *testReply) *testReply {
+	t.Helper()
+	select {
+	case r := <-replies:
+		return r
+	case <-time.After(testTimeout):
+		t.Fatal("receive timeout")
+	}
+	return nil
+}
+
+// testEventually 等待条件成立
+func testEventually(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// testError 解析错误包，不是错误包时返回 nil
+func testError(t *testing.T, r *testReply) *Error {
+	t.Helper()
+	if !r.flag.Has(message.FlagError) {
+		return nil
+	}
+	e := &Error{}
+	if err := ErrorBinder.Unmarshal(r.body, e); err != nil {
+		t.Fatalf("unmarshal error reply:%v", err)
+	}
+	return e
+}
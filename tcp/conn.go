@@ -2,6 +2,7 @@ package tcp
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -18,6 +19,7 @@ type Conn struct {
 	net.Conn
 	head []byte
 	buff *bytes.Buffer
+	cred *listener.Credentials // Unix domain socket 对端进程的身份
 }
 
 func (this *Conn) ReadMessage(_ listener.Socket, msg message.Message) error {
@@ -60,3 +62,19 @@ func (this *Conn) WriteMessage(_ listener.Socket, msg message.Message) error {
 	_, err = this.Conn.Write(this.buff.Bytes())
 	return err
 }
+
+// TLSConnectionState 返回 TLS 连接状态，实现 listener.TLSConn 接口。
+func (this *Conn) TLSConnectionState() (tls.ConnectionState, bool) {
+	if c, ok := this.Conn.(interface{ ConnectionState() tls.ConnectionState }); ok {
+		return c.ConnectionState(), true
+	}
+	return tls.ConnectionState{}, false
+}
+
+// PeerCredentials 返回 Unix domain socket 对端进程的身份，实现 listener.CredentialsConn 接口。
+func (this *Conn) PeerCredentials() (listener.Credentials, bool) {
+	if this.cred == nil {
+		return listener.Credentials{}, false
+	}
+	return *this.cred, true
+}
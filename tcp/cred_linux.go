@@ -0,0 +1,34 @@
+//go:build linux
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+// peerCredentials 通过 SO_PEERCRED 读取对端进程的身份
+func peerCredentials(conn net.Conn) (*listener.Credentials, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, errors.New("peer credentials require unix connection")
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var ucred *syscall.Ucred
+	var cerr error
+	if err = raw.Control(func(fd uintptr) {
+		ucred, cerr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	if cerr != nil {
+		return nil, cerr
+	}
+	return &listener.Credentials{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, nil
+}
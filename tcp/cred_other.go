@@ -0,0 +1,15 @@
+//go:build !linux
+
+package tcp
+
+import (
+	"errors"
+	"net"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+// peerCredentials 当前平台不支持读取对端进程的身份
+func peerCredentials(_ net.Conn) (*listener.Credentials, error) {
+	return nil, errors.New("peer credentials not supported on this platform")
+}
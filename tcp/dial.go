@@ -0,0 +1,51 @@
+package tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+// Dial 连接服务器，返回客户端模式的连接。
+// 参数:
+//   - network: "tcp", "tcp4", "tcp6", "unix"
+//   - address: 服务器地址，unix 为 socket 文件路径
+//   - timeout: 连接超时时间
+func Dial(network, address string, timeout time.Duration) (listener.Conn, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn), nil
+}
+
+// DialTLS 使用 TLS 连接服务器，握手完成后返回客户端模式的连接。
+// 参数:
+//   - network: "tcp", "tcp4", "tcp6"
+//   - address: 服务器地址
+//   - timeout: 连接和握手的总超时时间
+//   - tlsConfig: TLS 配置，可以为 nil；未设置 ServerName 时使用 address 中的主机名，双向认证时设置 Certificates
+func DialTLS(network, address string, timeout time.Duration, tlsConfig *tls.Config) (listener.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			tlsConfig.ServerName = host
+		} else {
+			tlsConfig.ServerName = address
+		}
+	}
+	d := &tls.Dialer{Config: tlsConfig}
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn), nil
+}
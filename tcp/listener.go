@@ -1,26 +1,199 @@
 package tcp
 
 import (
-	"github.com/hwcer/cosnet/listener"
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/logger"
 )
 
 func New(network, address string) (listener.Listener, error) {
+	return NewWithConfig(network, address, nil)
+}
+
+// NewWithConfig 使用指定配置创建监听器，config 为 nil 时使用 Options
+func NewWithConfig(network, address string, config *Config) (listener.Listener, error) {
+	return NewTLS(network, address, nil, config)
+}
+
+// NewTLS 创建使用 TLS 加密的监听器，握手完成后才交给 Accept，握手失败的连接直接关闭。
+// 参数:
+//   - network: "tcp", "tcp4", "tcp6"
+//   - address: 监听地址
+//   - tlsConfig: TLS 配置，nil 时不加密；SNI 证书选择和证书重新加载参考 listener.Certificates
+//   - config: 监听器配置，nil 时使用 Options
+func NewTLS(network, address string, tlsConfig *tls.Config, config *Config) (listener.Listener, error) {
+	if config == nil {
+		config = &Options
+	}
+	trusted, err := parseTrusted(config)
+	if err != nil {
+		return nil, err
+	}
 	ln, err := net.Listen(network, address)
 	if err != nil {
 		return nil, err
 	}
-	return &Listener{Listener: ln}, nil
+	return newListener(ln, tlsConfig, config, trusted), nil
+}
+
+// NewListener 使用已经创建的 net.Listener 创建监听器，用于端口复用（mux）等场景。
+// 参数:
+//   - ln: 底层监听器
+//   - tlsConfig: TLS 配置，nil 时不加密
+//   - config: 监听器配置，nil 时使用 Options
+func NewListener(ln net.Listener, tlsConfig *tls.Config, config *Config) (listener.Listener, error) {
+	if config == nil {
+		config = &Options
+	}
+	trusted, err := parseTrusted(config)
+	if err != nil {
+		return nil, err
+	}
+	return newListener(ln, tlsConfig, config, trusted), nil
+}
+
+// parseTrusted 解析 ProxyTrusted，开启 PROXY protocol 时不能为空
+func parseTrusted(config *Config) (listener.Trusted, error) {
+	if config.Proxy == ProxyModeOff {
+		return nil, nil
+	}
+	if len(config.ProxyTrusted) == 0 {
+		return nil, fmt.Errorf("tcp ProxyTrusted:%w", listener.ErrTrustedRequired)
+	}
+	return listener.ParseTrusted(config.ProxyTrusted)
+}
+
+func newListener(ln net.Listener, tlsConfig *tls.Config, config *Config, trusted listener.Trusted) *Listener {
+	l := &Listener{Listener: ln, config: config, trusted: trusted, tls: tlsConfig}
+	if config.Proxy != ProxyModeOff || tlsConfig != nil {
+		l.conns = make(chan net.Conn)
+		l.errs = make(chan error, 1)
+		go l.accept()
+	}
+	return l
 }
 
 type Listener struct {
 	net.Listener
+	config  *Config
+	trusted listener.Trusted
+	tls     *tls.Config
+	conns   chan net.Conn // 已经解析完 PROXY 头、完成 TLS 握手的连接
+	errs    chan error
 }
 
 func (ln *Listener) Accept() (listener.Conn, error) {
-	conn, err := ln.Listener.Accept()
-	if err == nil {
-		return NewConn(conn), nil
+	if ln.conns == nil {
+		conn, err := ln.Listener.Accept()
+		if err == nil {
+			return ln.newConn(conn), nil
+		}
+		return nil, err
+	}
+	select {
+	case conn := <-ln.conns:
+		return ln.newConn(conn), nil
+	case err := <-ln.errs:
+		ln.errs <- err
+		return nil, err
+	}
+}
+
+// newConn 创建连接，Unix domain socket 按配置读取对端进程的身份
+func (ln *Listener) newConn(conn net.Conn) *Conn {
+	c := NewConn(conn)
+	if ln.config.UnixCredentials {
+		if _, ok := conn.(*net.UnixConn); ok {
+			cred, err := peerCredentials(conn)
+			if err != nil {
+				logger.Debug("unix peer credentials %v error:%v", conn.RemoteAddr(), err)
+			}
+			c.cred = cred
+		}
+	}
+	return c
+}
+
+// accept 接受连接后在独立的协程中读取 PROXY 头和 TLS 握手，避免慢连接阻塞其它连接
+func (ln *Listener) accept() {
+	for {
+		conn, err := ln.Listener.Accept()
+		if err != nil {
+			ln.errs <- err
+			return
+		}
+		go ln.handshake(conn)
+	}
+}
+
+func (ln *Listener) handshake(conn net.Conn) {
+	if ln.config.Proxy != ProxyModeOff {
+		if conn = ln.proxy(conn); conn == nil {
+			return
+		}
+	}
+	if ln.tls != nil {
+		timeout := ln.config.TLSHandshakeTimeout
+		if timeout <= 0 {
+			timeout = Options.TLSHandshakeTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		tc := tls.Server(conn, ln.tls)
+		err := tc.HandshakeContext(ctx)
+		cancel()
+		if err != nil {
+			logger.Debug("tls handshake %v error:%v", conn.RemoteAddr(), err)
+			_ = conn.Close()
+			return
+		}
+		conn = tc
+	}
+	ln.deliver(conn)
+}
+
+// proxy 读取 PROXY 头，连接被拒绝时返回 nil
+func (ln *Listener) proxy(conn net.Conn) net.Conn {
+	if !ln.trusted.Contains(conn.RemoteAddr()) {
+		if ln.config.Proxy == ProxyModeRequired {
+			logger.Debug("proxy protocol untrusted upstream:%v", conn.RemoteAddr())
+			_ = conn.Close()
+			return nil
+		}
+		return conn
+	}
+	timeout := ln.config.ProxyTimeout
+	if timeout <= 0 {
+		timeout = Options.ProxyTimeout
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	r := bufio.NewReader(conn)
+	remote, local, err := proxyRead(r, ln.config.Proxy == ProxyModeRequired)
+	var ne net.Error
+	if err != nil && ln.config.Proxy == ProxyModeOptional && r.Buffered() == 0 && errors.As(err, &ne) && ne.Timeout() {
+		err = nil // 对端在超时时间内没有发送任何数据，按普通连接处理
+	}
+	if err != nil {
+		logger.Debug("proxy protocol %v error:%v", conn.RemoteAddr(), err)
+		_ = conn.Close()
+		return nil
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	return &proxyConn{Conn: conn, r: r, remote: remote, local: local}
+}
+
+// deliver 交给 Accept，监听器关闭后关闭连接
+func (ln *Listener) deliver(conn net.Conn) {
+	select {
+	case ln.conns <- conn:
+	case err := <-ln.errs:
+		ln.errs <- err
+		_ = conn.Close()
 	}
-	return nil, err
 }
@@ -0,0 +1,40 @@
+package tcp
+
+import (
+	"os"
+	"time"
+)
+
+// ProxyMode PROXY protocol（HAProxy v1/v2）处理方式。
+type ProxyMode int8
+
+// PROXY protocol 处理方式常量。
+const (
+	ProxyModeOff      ProxyMode = iota // 不解析（默认）
+	ProxyModeOptional                  // 受信任的上游发送了 PROXY 头时解析，没有时按普通连接处理
+	ProxyModeRequired                  // 受信任的上游必须发送 PROXY 头，否则关闭连接；不受信任的上游直接关闭
+)
+
+// Config TCP模块配置，可以通过 NewWithConfig 为每个监听器单独设置
+type Config struct {
+	// Proxy PROXY protocol 处理方式
+	Proxy ProxyMode
+	// ProxyTrusted 允许发送 PROXY 头的上游地址（IP 或 CIDR），开启 Proxy 时必须设置，否则创建监听器失败
+	ProxyTrusted []string
+	// ProxyTimeout 读取 PROXY 头的超时时间，ProxyModeOptional 模式下对端超时仍未发送数据时按普通连接处理
+	ProxyTimeout time.Duration
+	// TLSHandshakeTimeout TLS 握手超时时间，仅 NewTLS 创建的监听器使用
+	TLSHandshakeTimeout time.Duration
+	// UnixMode Unix domain socket 文件的权限，0 表示不修改（由 umask 决定），抽象命名空间地址忽略
+	// 设置后先在同目录下权限为 0700 的临时目录中创建 socket 并设置权限，再移动到目标路径，文件不会以其它权限出现
+	UnixMode os.FileMode
+	// UnixCredentials 是否在接受 Unix domain socket 连接时读取对端进程的身份（uid/gid/pid），仅 Linux 支持
+	UnixCredentials bool
+}
+
+// Options TCP模块默认配置选项
+var Options = Config{
+	Proxy:               ProxyModeOff,    // 默认不解析 PROXY 头
+	ProxyTimeout:        5 * time.Second, // 5 秒内未收到完整的 PROXY 头关闭连接
+	TLSHandshakeTimeout: 5 * time.Second, // 5 秒内未完成 TLS 握手关闭连接
+}
@@ -0,0 +1,180 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ErrProxyHeader PROXY 头格式错误。
+var ErrProxyHeader = errors.New("proxy protocol header invalid")
+
+// ErrProxyRequired 连接没有发送 PROXY 头。
+var ErrProxyRequired = errors.New("proxy protocol header required")
+
+// proxyV1Prefix PROXY protocol v1 的前缀
+var proxyV1Prefix = []byte("PROXY ")
+
+// proxyV2Signature PROXY protocol v2 的签名
+var proxyV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// proxyV1MaxSize v1 头的最大长度，包含 \r\n
+const proxyV1MaxSize = 107
+
+// proxyConn 解析过 PROXY 头的连接，读取时先消费缓存的数据
+type proxyConn struct {
+	net.Conn
+	r      *bufio.Reader
+	remote net.Addr
+	local  net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// RemoteAddr 返回 PROXY 头中的客户端地址。
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr 返回 PROXY 头中的目标地址。
+func (c *proxyConn) LocalAddr() net.Addr {
+	if c.local != nil {
+		return c.local
+	}
+	return c.Conn.LocalAddr()
+}
+
+// proxyDetect 检查缓存中是否为 PROXY 头，逐字节比较，不匹配时立即返回，不会等待更多数据
+func proxyDetect(r *bufio.Reader) (version int, err error) {
+	for _, sig := range [][]byte{proxyV1Prefix, proxyV2Signature} {
+		matched := true
+		for i := 1; i <= len(sig); i++ {
+			var b []byte
+			if b, err = r.Peek(i); err != nil {
+				return 0, err
+			}
+			if b[i-1] != sig[i-1] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			if sig[0] == 'P' {
+				return 1, nil
+			}
+			return 2, nil
+		}
+	}
+	return 0, nil
+}
+
+// proxyRead 读取 PROXY 头，返回客户端地址和目标地址；required 为 false 时没有 PROXY 头返回 nil 地址
+func proxyRead(r *bufio.Reader, required bool) (remote, local net.Addr, err error) {
+	version, err := proxyDetect(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch version {
+	case 1:
+		return proxyReadV1(r)
+	case 2:
+		return proxyReadV2(r)
+	}
+	if required {
+		return nil, nil, ErrProxyRequired
+	}
+	return nil, nil, nil
+}
+
+// proxyReadV1 解析文本格式：PROXY TCP4 源地址 目标地址 源端口 目标端口\r\n
+func proxyReadV1(r *bufio.Reader) (remote, local net.Addr, err error) {
+	var line []byte
+	for len(line) < proxyV1MaxSize {
+		var b byte
+		if b, err = r.ReadByte(); err != nil {
+			return nil, nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, nil, ErrProxyHeader
+	}
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) < 2 {
+		return nil, nil, ErrProxyHeader
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil, nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, nil, ErrProxyHeader
+	}
+	if len(fields) != 6 {
+		return nil, nil, ErrProxyHeader
+	}
+	src, dst := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	sport, e1 := strconv.ParseUint(fields[4], 10, 16)
+	dport, e2 := strconv.ParseUint(fields[5], 10, 16)
+	if src == nil || dst == nil || e1 != nil || e2 != nil {
+		return nil, nil, ErrProxyHeader
+	}
+	return &net.TCPAddr{IP: src, Port: int(sport)}, &net.TCPAddr{IP: dst, Port: int(dport)}, nil
+}
+
+// proxyReadV2 解析二进制格式：签名(12) + 版本和命令(1) + 协议族(1) + 地址长度(2) + 地址
+func proxyReadV2(r *bufio.Reader) (remote, local net.Addr, err error) {
+	head := make([]byte, 16)
+	if _, err = io.ReadFull(r, head); err != nil {
+		return nil, nil, err
+	}
+	if head[12]>>4 != 2 {
+		return nil, nil, ErrProxyHeader
+	}
+	command := head[12] & 0x0F
+	family := head[13]
+	data := make([]byte, binary.BigEndian.Uint16(head[14:16]))
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, nil, err
+	}
+	switch command {
+	case 0x0: // LOCAL，健康检查等由代理自己发起的连接，使用真实地址
+		return nil, nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, nil, ErrProxyHeader
+	}
+	var size int
+	switch family >> 4 {
+	case 0x1: // AF_INET
+		size = net.IPv4len
+	case 0x2: // AF_INET6
+		size = net.IPv6len
+	default: // AF_UNSPEC、AF_UNIX，忽略地址
+		return nil, nil, nil
+	}
+	if len(data) < size*2+4 {
+		return nil, nil, ErrProxyHeader
+	}
+	src := net.IP(append([]byte(nil), data[:size]...))
+	dst := net.IP(append([]byte(nil), data[size:size*2]...))
+	sport := int(binary.BigEndian.Uint16(data[size*2:]))
+	dport := int(binary.BigEndian.Uint16(data[size*2+2:]))
+	if family&0x0F == 0x2 { // DGRAM
+		return &net.UDPAddr{IP: src, Port: sport}, &net.UDPAddr{IP: dst, Port: dport}, nil
+	}
+	return &net.TCPAddr{IP: src, Port: sport}, &net.TCPAddr{IP: dst, Port: dport}, nil
+}
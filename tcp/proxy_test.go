@@ -0,0 +1,113 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+func TestProxyV1(t *testing.T) {
+	r := bufio.NewReader(bytes.NewBufferString("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\npayload"))
+	remote, local, err := proxyRead(r, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote.String() != "192.0.2.1:56324" || local.String() != "198.51.100.1:443" {
+		t.Fatalf("got %v %v", remote, local)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "payload" {
+		t.Fatalf("payload not preserved: %q", rest)
+	}
+
+	r = bufio.NewReader(bytes.NewBufferString("PROXY UNKNOWN\r\n"))
+	if remote, _, err = proxyRead(r, true); err != nil || remote != nil {
+		t.Fatalf("UNKNOWN: %v %v", remote, err)
+	}
+
+	r = bufio.NewReader(bytes.NewBufferString("PROXY TCP4 bad\r\n"))
+	if _, _, err = proxyRead(r, true); err != ErrProxyHeader {
+		t.Fatalf("expected ErrProxyHeader, got %v", err)
+	}
+}
+
+func TestProxyV2(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(proxyV2Signature)
+	buf.Write([]byte{0x21, 0x21}) // v2 PROXY, AF_INET6 STREAM
+	_ = binary.Write(&buf, binary.BigEndian, uint16(36))
+	buf.Write(net.ParseIP("2001:db8::1"))
+	buf.Write(net.ParseIP("2001:db8::2"))
+	_ = binary.Write(&buf, binary.BigEndian, uint16(1234))
+	_ = binary.Write(&buf, binary.BigEndian, uint16(443))
+	buf.WriteString("\xf0rest")
+	r := bufio.NewReader(&buf)
+	remote, local, err := proxyRead(r, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote.String() != "[2001:db8::1]:1234" || local.String() != "[2001:db8::2]:443" {
+		t.Fatalf("got %v %v", remote, local)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "\xf0rest" {
+		t.Fatalf("payload not preserved: %q", rest)
+	}
+}
+
+func TestProxyOptional(t *testing.T) {
+	// 普通连接的第一个字节是消息魔数，不会等待更多数据
+	r := bufio.NewReader(bytes.NewBuffer([]byte{0xf0, 0x00}))
+	remote, _, err := proxyRead(r, false)
+	if err != nil || remote != nil {
+		t.Fatalf("got %v %v", remote, err)
+	}
+	if r.Buffered() != 2 {
+		t.Fatalf("data consumed: %d", r.Buffered())
+	}
+	if _, _, err = proxyRead(bufio.NewReader(bytes.NewBuffer([]byte{0xf0})), true); err != ErrProxyRequired {
+		t.Fatalf("expected ErrProxyRequired, got %v", err)
+	}
+}
+
+func TestListenerProxy(t *testing.T) {
+	ln, err := NewWithConfig("tcp", "127.0.0.1:0", &Config{Proxy: ProxyModeRequired, ProxyTrusted: []string{"127.0.0.0/8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err == nil {
+			_, _ = c.Write([]byte("PROXY TCP4 203.0.113.7 127.0.0.1 4000 80\r\nhi"))
+		}
+	}()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.RemoteAddr().String() != "203.0.113.7:4000" {
+		t.Fatalf("remote %v", conn.RemoteAddr())
+	}
+	b := make([]byte, 2)
+	if _, err = io.ReadFull(conn, b); err != nil || string(b) != "hi" {
+		t.Fatalf("read %q %v", b, err)
+	}
+}
+
+func TestProxyTrustedRequired(t *testing.T) {
+	for _, mode := range []ProxyMode{ProxyModeOptional, ProxyModeRequired} {
+		if _, err := NewWithConfig("tcp", "127.0.0.1:0", &Config{Proxy: mode}); !errors.Is(err, listener.ErrTrustedRequired) {
+			t.Fatalf("mode %d without ProxyTrusted:%v", mode, err)
+		}
+	}
+	var trusted listener.Trusted
+	if trusted.Contains(&net.TCPAddr{IP: net.ParseIP("127.0.0.1")}) {
+		t.Fatal("empty trusted list contains address")
+	}
+}
@@ -0,0 +1,122 @@
+package tcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+// issue 签发证书，parent 为 nil 时自签名
+func issue(t *testing.T, cn string, dns []string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dns,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		tpl.IsCA, tpl.BasicConstraintsValid = true, true
+		parent, parentKey = tpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	kb, _ := x509.MarshalECPrivateKey(key)
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb})
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, _, _ := issue(t, "ca", nil, nil, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	write := func(name string, cert, key []byte) (string, string) {
+		c, k := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+		_ = os.WriteFile(c, cert, 0600)
+		_ = os.WriteFile(k, key, 0600)
+		return c, k
+	}
+	_, _, c1, k1 := issue(t, "a.example.com", []string{"a.example.com"}, ca, caKey)
+	_, _, c2, k2 := issue(t, "b", []string{"*.b.example.com"}, ca, caKey)
+	_, _, cc, ck := issue(t, "service-1", []string{"svc.internal"}, ca, caKey)
+	certA, keyA := write("a", c1, k1)
+	certB, keyB := write("b", c2, k2)
+
+	certs := listener.NewCertificates()
+	if err := certs.Load(certA, keyA); err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.Load(certB, keyB); err != nil {
+		t.Fatal(err)
+	}
+	srvConfig := certs.TLSConfig()
+	srvConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	srvConfig.ClientCAs = pool
+	ln, err := NewTLS("tcp", "127.0.0.1:0", srvConfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err := tls.X509KeyPair(cc, ck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := func(name string) *x509.Certificate {
+		t.Helper()
+		cfg := &tls.Config{RootCAs: pool, ServerName: name, Certificates: []tls.Certificate{client}}
+		conn, err := DialTLS("tcp", ln.Addr().String(), time.Second, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		sc, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sc.Close()
+		state, ok := sc.(listener.TLSConn).TLSConnectionState()
+		if !ok || len(state.PeerCertificates) == 0 || state.PeerCertificates[0].Subject.CommonName != "service-1" {
+			t.Fatalf("peer identity missing: %v %v", ok, state.PeerCertificates)
+		}
+		cs, _ := conn.(listener.TLSConn).TLSConnectionState()
+		return cs.PeerCertificates[0]
+	}
+	if got := dial("a.example.com").Subject.CommonName; got != "a.example.com" {
+		t.Fatalf("sni a: %v", got)
+	}
+	if got := dial("x.b.example.com").Subject.CommonName; got != "b" {
+		t.Fatalf("sni wildcard: %v", got)
+	}
+
+	// 替换证书文件后重新加载
+	_, _, c3, k3 := issue(t, "a2", []string{"a.example.com"}, ca, caKey)
+	write("a", c3, k3)
+	if err = certs.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := dial("a.example.com").Subject.CommonName; got != "a2" {
+		t.Fatalf("reload: %v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+// ErrUnixAddressInUse socket 文件已经被其它正在运行的进程使用。
+var ErrUnixAddressInUse = errors.New("unix socket address already in use")
+
+// NewUnix 创建 Unix domain socket 监听器，消息格式与 TCP 相同。
+// 参数:
+//   - address: socket 文件路径，以 @ 开头时使用 Linux 抽象命名空间，不创建文件
+//   - config: 监听器配置（文件权限、对端身份），nil 时使用 Options
+//
+// 返回值: socket 文件被其它正在运行的进程使用时返回 ErrUnixAddressInUse
+func NewUnix(address string, config *Config) (listener.Listener, error) {
+	if config == nil {
+		config = &Options
+	}
+	abstract := len(address) > 0 && address[0] == '@'
+	if !abstract {
+		if err := unixCleanup(address); err != nil {
+			return nil, err
+		}
+	}
+	var ln net.Listener
+	var err error
+	if abstract || config.UnixMode == 0 {
+		ln, err = net.Listen("unix", address)
+	} else {
+		ln, err = unixListen(address, config.UnixMode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{Listener: ln, config: config}, nil
+}
+
+// unixListen 在权限为 0700 的临时目录中创建 socket 文件，设置权限后再移动到 address，
+// 避免 socket 文件在设置权限之前以 umask 决定的权限短暂可见
+func unixListen(address string, mode os.FileMode) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(address), ".cosnet-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	ul := ln.(*net.UnixListener)
+	ul.SetUnlinkOnClose(false)
+	if err = os.Chmod(tmp, mode); err == nil {
+		err = os.Rename(tmp, address)
+	}
+	if err != nil {
+		_ = ul.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: ul, path: address}, nil
+}
+
+// unixListener 移动过 socket 文件的监听器，地址和关闭时删除的文件使用移动后的路径
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	_ = os.Remove(l.path)
+	return err
+}
+
+// unixCleanup 删除上次进程异常退出时遗留的 socket 文件，文件不是 socket 或者仍然有进程在监听时返回错误
+func unixCleanup(address string) error {
+	info, err := os.Lstat(address)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("unix socket address %v exists and is not a socket", address)
+	}
+	if conn, err := net.DialTimeout("unix", address, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%w:%v", ErrUnixAddressInUse, address)
+	}
+	return os.Remove(address)
+}
@@ -0,0 +1,113 @@
+package tcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+func TestUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cosnet.sock")
+	// 模拟进程异常退出后遗留的 socket 文件
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ln, err := NewUnix(path, &Config{UnixMode: 0600, UnixCredentials: true})
+	if err != nil {
+		t.Fatalf("stale socket file not removed:%v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("mode %v", info.Mode().Perm())
+	}
+	if _, err = NewUnix(path, nil); !errors.Is(err, ErrUnixAddressInUse) {
+		t.Fatalf("expected address in use, got %v", err)
+	}
+
+	accepted := make(chan listener.Conn, 1)
+	go func() {
+		if c, e := ln.Accept(); e == nil {
+			accepted <- c
+		}
+	}()
+	client, err := Dial("unix", path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+	var conn listener.Conn
+	select {
+	case conn = <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("accept timeout")
+	}
+	defer func() { _ = conn.Close() }()
+	cred, ok := conn.(listener.CredentialsConn).PeerCredentials()
+	if runtime.GOOS != "linux" {
+		return
+	}
+	if !ok || cred.PID != int32(os.Getpid()) || cred.UID != uint32(os.Getuid()) {
+		t.Fatalf("credentials %+v %v", cred, ok)
+	}
+}
+
+func TestUnixAbstract(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract namespace requires linux")
+	}
+	address := "@cosnet-test-" + time.Now().Format("150405.000000000")
+	ln, err := NewUnix(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	go func() {
+		if c, e := ln.Accept(); e == nil {
+			_ = c.Close()
+		}
+	}()
+	client, err := Dial("unix", address, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Close()
+}
+
+func TestUnixMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cosnet.sock")
+	ln, err := NewUnix(path, &Config{UnixMode: 0600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ln.Addr().String() != path {
+		t.Fatalf("addr %v", ln.Addr())
+	}
+	// 创建 socket 使用的临时目录已经删除
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("entries %v", entries)
+	}
+	client, err := Dial("unix", path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Close()
+	_ = ln.Close()
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("socket file not removed on close:%v", err)
+	}
+}
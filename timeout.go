@@ -0,0 +1,57 @@
+package cosnet
+
+import (
+	"sync/atomic"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+// SetTimeout 设置当前连接没有动作被判断为掉线的时间，优先级高于其它所有规则。
+// 参数 seconds: 超时时间，单位秒，0 表示恢复默认规则。
+func (sock *Socket) SetTimeout(seconds int32) {
+	atomic.StoreInt32(&sock.timeout, seconds)
+}
+
+// Timeout 获取当前连接没有动作被判断为掉线的时间，单位秒，0 表示不检测。
+// 按以下顺序取第一个有效值:
+//   - Socket.SetTimeout 设置的值
+//   - 服务器模式下未认证的连接使用 SocketHandshakeTime
+//   - 已认证的连接按 SocketRoleKey 取角色，使用 SocketRoleTime 中配置的值
+//   - Sockets.SetListenerTimeout 为接受该连接的监听器设置的值
+//   - SocketConnectTime
+//
+// 未认证的连接另外受 AuthenticationTimeout 限制（按连接时长计算，不因收到消息重新计时），两者先满足的一方关闭连接。
+func (sock *Socket) Timeout() int32 {
+	if v := atomic.LoadInt32(&sock.timeout); v > 0 {
+		return v
+	}
+	opts := &sock.sockets.Options
+	data := sock.data.Load()
+	if data == nil {
+		if opts.SocketHandshakeTime > 0 && sock.Type() == listener.SocketTypeServer {
+			return opts.SocketHandshakeTime
+		}
+	} else if opts.SocketRoleKey != "" {
+		if v := opts.SocketRoleTime[data.GetString(opts.SocketRoleKey)]; v > 0 {
+			return v
+		}
+	}
+	if sock.listener != nil {
+		if v, ok := sock.sockets.timeouts.Load(sock.listener); ok {
+			return v.(int32)
+		}
+	}
+	return opts.SocketConnectTime
+}
+
+// SetListenerTimeout 设置通过指定监听器接入的连接没有动作被判断为掉线的时间。
+// 参数:
+//   - ln: 监听器实例
+//   - seconds: 超时时间，单位秒，0 表示恢复默认规则
+func (ss *Sockets) SetListenerTimeout(ln listener.Listener, seconds int32) {
+	if seconds > 0 {
+		ss.timeouts.Store(ln, seconds)
+	} else {
+		ss.timeouts.Delete(ln)
+	}
+}
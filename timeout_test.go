@@ -0,0 +1,80 @@
+package cosnet
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/hwcer/cosgo/session"
+)
+
+func TestTimeoutPrecedence(t *testing.T) {
+	ss, address := testServer(t, func(ss *Sockets) {
+		ss.Options.SocketConnectTime = 60
+		ss.Options.SocketHandshakeTime = 10
+		ss.Options.SocketRoleKey = "role"
+		ss.Options.SocketRoleTime = map[string]int32{"bot": 300}
+	})
+	testClient(t, address)
+	sock := testServerSocket(t, ss)
+	if v := sock.Timeout(); v != 10 {
+		t.Fatalf("unauthenticated timeout %d", v)
+	}
+	sock.Authentication(session.NewData("u1", nil))
+	if v := sock.Timeout(); v != 60 {
+		t.Fatalf("authenticated timeout %d", v)
+	}
+	sock.Authentication(session.NewData("u2", map[string]any{"role": "bot"}))
+	if v := sock.Timeout(); v != 300 {
+		t.Fatalf("role timeout %d", v)
+	}
+	sock.SetTimeout(5)
+	if v := sock.Timeout(); v != 5 {
+		t.Fatalf("SetTimeout %d", v)
+	}
+}
+
+func TestHandshakeAndAuthenticationTimeout(t *testing.T) {
+	setup := func(handshake, authentication int32, unauthorized *int32) func(ss *Sockets) {
+		return func(ss *Sockets) {
+			ss.Options.SocketHandshakeTime = handshake
+			ss.Options.AuthenticationTimeout = authentication
+			ss.On(EventTypeUnauthorized, func(*Socket, any) {
+				atomic.AddInt32(unauthorized, 1)
+			})
+		}
+	}
+	closed := func(sock *Socket) bool {
+		return sock.Status() != SocketStatusConnected
+	}
+
+	// 空闲超过 SocketHandshakeTime 先关闭，不触发 EventTypeUnauthorized
+	t.Run("handshake", func(t *testing.T) {
+		var unauthorized int32
+		ss, address := testServer(t, setup(2, 10, &unauthorized))
+		testClient(t, address)
+		sock := testServerSocket(t, ss)
+		sock.Heartbeat(3)
+		if !closed(sock) || atomic.LoadInt32(&unauthorized) != 0 {
+			t.Fatalf("handshake timeout,closed:%v,unauthorized:%d", closed(sock), unauthorized)
+		}
+	})
+
+	// 持续有消息时 SocketHandshakeTime 不会触发，由 AuthenticationTimeout 关闭
+	t.Run("authentication", func(t *testing.T) {
+		var unauthorized int32
+		ss, address := testServer(t, setup(3, 4, &unauthorized))
+		testClient(t, address)
+		sock := testServerSocket(t, ss)
+		sock.Heartbeat(2)
+		sock.KeepAlive()
+		sock.Heartbeat(2)
+		if closed(sock) {
+			t.Fatal("closed before AuthenticationTimeout")
+		}
+		sock.KeepAlive()
+		sock.Heartbeat(2)
+		if !closed(sock) || atomic.LoadInt32(&unauthorized) != 1 {
+			t.Fatalf("authentication timeout,closed:%v,unauthorized:%d", closed(sock), unauthorized)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package cosnet
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+// PeerIdentity 双向认证时对端证书中的身份信息，用于服务间认证。
+type PeerIdentity struct {
+	Subject        pkix.Name         // 证书主体
+	DNSNames       []string          // SAN 中的域名
+	IPAddresses    []net.IP          // SAN 中的 IP
+	URIs           []*url.URL        // SAN 中的 URI，例如 SPIFFE ID
+	EmailAddresses []string          // SAN 中的邮箱
+	Certificate    *x509.Certificate // 对端证书
+}
+
+// TLS 获取 TLS 连接状态。
+// 返回值: 连接没有使用 TLS（tls、tcps、wss）时第二个返回值为 false。
+func (sock *Socket) TLS() (tls.ConnectionState, bool) {
+	if c, ok := sock.Conn().(listener.TLSConn); ok {
+		return c.TLSConnectionState()
+	}
+	return tls.ConnectionState{}, false
+}
+
+// PeerIdentity 获取对端证书中的身份信息。
+// 服务器模式下需要 tls.Config.ClientAuth 要求并校验客户端证书，否则对端可能没有证书或证书未经校验。
+// 返回值: 没有使用 TLS 或对端没有提供证书时返回 nil。
+func (sock *Socket) PeerIdentity() *PeerIdentity {
+	state, ok := sock.TLS()
+	if !ok || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := state.PeerCertificates[0]
+	return &PeerIdentity{
+		Subject:        cert.Subject,
+		DNSNames:       cert.DNSNames,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+		EmailAddresses: cert.EmailAddresses,
+		Certificate:    cert,
+	}
+}
@@ -0,0 +1,531 @@
+package udp
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/hwcer/cosnet/message"
+	"github.com/hwcer/logger"
+)
+
+// 可靠模式的帧类型
+const (
+	arqCmdData byte = 0x51 // 可靠数据
+	arqCmdAck  byte = 0x52 // 确认
+	arqCmdRaw  byte = 0x53 // 不可靠数据
+)
+
+// arqHeadSize 帧头长度: [4 conv][1 cmd][1 frg][2 wnd][4 ts][4 seq][4 una][2 len]
+const arqHeadSize = 22
+
+// ErrDeadLink 数据包重传次数超过 Config.MaxRetransmit，认为连接已断开。
+var ErrDeadLink = errors.New("udp reliable link dead")
+
+// ErrMessageTooLarge 消息分片数量超过 255 或接收窗口。
+var ErrMessageTooLarge = errors.New("udp reliable message too large")
+
+type arqSegment struct {
+	conv uint32
+	cmd  byte
+	frg  uint8 // 剩余分片数量，0 表示消息的最后一个分片
+	wnd  uint16
+	ts   uint32
+	seq  uint32
+	una  uint32
+	data []byte
+
+	resendAt uint32 // 下次重传的时间
+	rto      uint32
+	xmit     int32 // 发送次数
+	fastack  int32 // 被跨越确认的次数
+}
+
+func (s *arqSegment) encode(b []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, s.conv)
+	b = append(b, s.cmd, s.frg)
+	b = binary.BigEndian.AppendUint16(b, s.wnd)
+	b = binary.BigEndian.AppendUint32(b, s.ts)
+	b = binary.BigEndian.AppendUint32(b, s.seq)
+	b = binary.BigEndian.AppendUint32(b, s.una)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s.data)))
+	return append(b, s.data...)
+}
+
+// arqConv 读取数据包的会话编号
+func arqConv(b []byte) (uint32, bool) {
+	if len(b) < arqHeadSize {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(b), true
+}
+
+// arqFirst 数据包是否可能是新会话的第一个数据包（序号为 0 的数据或者不可靠数据）
+func arqFirst(b []byte) bool {
+	switch b[4] {
+	case arqCmdRaw:
+		return true
+	case arqCmdData:
+		return binary.BigEndian.Uint32(b[12:]) == 0
+	default:
+		return false
+	}
+}
+
+type arqAck struct {
+	seq uint32
+	ts  uint32
+}
+
+// arq 可靠传输，使用序号、累计确认加选择确认、超时和快速重传、滑动窗口流控，可选拥塞控制。
+// 一个 UDP 数据包中可以合并多个帧，不可靠数据使用独立的帧类型，不占用序号。
+type arq struct {
+	conv   uint32
+	config *Config
+	output func([]byte) error
+	start  time.Time
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	mss    int
+	wnd    uint32
+
+	sndNxt   uint32
+	sndUna   uint32
+	sndQueue []*arqSegment // 等待窗口的数据
+	sndBuf   []*arqSegment // 已发送未确认，按序号排列
+	rcvNxt   uint32
+	rcvBuf   []*arqSegment // 乱序到达，按序号排列
+	rcvQueue []*arqSegment // 按序到达等待读取
+	raws     [][]byte      // 不可靠数据
+	acks     []arqAck      // 等待发送的确认
+	buff     []byte
+
+	rmtWnd   uint32
+	cwnd     uint32
+	ssthresh uint32
+	incr     uint32
+	srtt     int32
+	rttvar   int32
+	rto      uint32
+
+	idle bool          // 定时器已停止，等待 wake
+	wake chan struct{} // 空闲时有新的数据需要发送或确认
+	err  error
+	done chan struct{}
+}
+
+// newArq 创建可靠传输，conv 为 0 时随机生成
+func newArq(conv uint32, config *Config, output func([]byte) error) *arq {
+	if conv == 0 {
+		conv = rand.Uint32() | 1
+	}
+	a := &arq{
+		conv:     conv,
+		config:   config,
+		output:   output,
+		start:    time.Now(),
+		mss:      int(config.MTU) - arqHeadSize,
+		wnd:      uint32(config.Window),
+		rmtWnd:   uint32(config.Window),
+		cwnd:     1,
+		ssthresh: uint32(config.Window),
+		rto:      200,
+		idle:     true,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mutex)
+	go a.run()
+	return a
+}
+
+func (a *arq) now() uint32 {
+	return uint32(time.Since(a.start).Milliseconds())
+}
+
+// run 按 Config.Interval 定时 flush，没有需要发送、重传或确认的数据时停止定时器，
+// 由 schedule 唤醒，避免大量空闲连接频繁唤醒
+func (a *arq) run() {
+	ticker := time.NewTicker(a.config.Interval)
+	ticker.Stop()
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-a.wake:
+		}
+		ticker.Reset(a.config.Interval)
+		for idle := false; !idle; {
+			select {
+			case <-a.done:
+				return
+			case <-ticker.C:
+			}
+			a.mutex.Lock()
+			a.flush()
+			a.idle = !a.busy()
+			idle = a.idle
+			a.mutex.Unlock()
+		}
+		ticker.Stop()
+	}
+}
+
+// busy 是否有等待发送、等待确认或等待回复的确认，调用者需要持有锁
+func (a *arq) busy() bool {
+	return len(a.sndQueue) > 0 || len(a.sndBuf) > 0 || len(a.acks) > 0
+}
+
+// schedule 空闲时有新的数据需要处理则唤醒定时器，调用者需要持有锁
+func (a *arq) schedule() {
+	if !a.idle || !a.busy() {
+		return
+	}
+	a.idle = false
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close 关闭后 send 和 recv 返回 err
+func (a *arq) close(err error) {
+	a.mutex.Lock()
+	a.fail(err)
+	a.mutex.Unlock()
+}
+
+// fail 记录第一个错误并唤醒等待的读写，调用者需要持有锁
+func (a *arq) fail(err error) {
+	if a.err != nil {
+		return
+	}
+	a.err = err
+	close(a.done)
+	a.cond.Broadcast()
+}
+
+// send 可靠发送消息，超过 MTU 时分片，发送队列超过 2 倍窗口时阻塞
+func (a *arq) send(data []byte) error {
+	count := (len(data) + a.mss - 1) / a.mss
+	if count == 0 {
+		count = 1
+	}
+	if count > 255 || uint32(count) > a.wnd {
+		return ErrMessageTooLarge
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for a.err == nil && len(a.sndQueue) > 0 && uint32(len(a.sndQueue)+count) > 2*a.wnd {
+		a.cond.Wait()
+	}
+	if a.err != nil {
+		return a.err
+	}
+	for i := 0; i < count; i++ {
+		size := min(len(data), a.mss)
+		a.sndQueue = append(a.sndQueue, &arqSegment{conv: a.conv, cmd: arqCmdData, frg: uint8(count - 1 - i), data: data[:size]})
+		data = data[size:]
+	}
+	a.flush()
+	a.schedule()
+	return a.err
+}
+
+// sendMessage 按 Config.Unreliable 选择可靠或不可靠通道发送消息，b 为消息编码后的数据
+func (a *arq) sendMessage(msg message.Message, b []byte) error {
+	if a.config.Unreliable != nil && a.config.Unreliable(msg) {
+		return a.sendRaw(b)
+	}
+	return a.send(b)
+}
+
+// sendRaw 不可靠发送消息，不分片、不重传
+func (a *arq) sendRaw(data []byte) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	seg := &arqSegment{conv: a.conv, cmd: arqCmdRaw, data: data}
+	return a.output(seg.encode(make([]byte, 0, arqHeadSize+len(data))))
+}
+
+// recv 读取下一条消息，不可靠数据优先
+func (a *arq) recv() ([]byte, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for {
+		if len(a.raws) > 0 {
+			b := a.raws[0]
+			a.raws = a.raws[1:]
+			return b, nil
+		}
+		for i, seg := range a.rcvQueue {
+			if seg.frg != 0 {
+				continue
+			}
+			var b []byte
+			if i == 0 {
+				b = seg.data
+			} else {
+				for _, s := range a.rcvQueue[:i+1] {
+					b = append(b, s.data...)
+				}
+			}
+			a.rcvQueue = a.rcvQueue[i+1:]
+			a.moveRcv()
+			return b, nil
+		}
+		if a.err != nil {
+			return nil, a.err
+		}
+		a.cond.Wait()
+	}
+}
+
+// input 处理收到的数据包
+func (a *arq) input(b []byte) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.err != nil {
+		return
+	}
+	prevUna := a.sndUna
+	var maxAck uint32
+	var acked bool
+	for len(b) >= arqHeadSize {
+		seg := &arqSegment{
+			conv: binary.BigEndian.Uint32(b),
+			cmd:  b[4],
+			frg:  b[5],
+			wnd:  binary.BigEndian.Uint16(b[6:]),
+			ts:   binary.BigEndian.Uint32(b[8:]),
+			seq:  binary.BigEndian.Uint32(b[12:]),
+			una:  binary.BigEndian.Uint32(b[16:]),
+		}
+		n := int(binary.BigEndian.Uint16(b[20:]))
+		if seg.conv != a.conv || len(b) < arqHeadSize+n {
+			return
+		}
+		seg.data = b[arqHeadSize : arqHeadSize+n]
+		b = b[arqHeadSize+n:]
+		if seg.cmd == arqCmdRaw {
+			if len(a.raws) < int(a.config.MsgChanSize) {
+				a.raws = append(a.raws, seg.data)
+			} else {
+				logger.Debug("udp reliable raw queue full, drop msg")
+			}
+			continue
+		}
+		a.rmtWnd = uint32(seg.wnd)
+		a.parseUna(seg.una)
+		switch seg.cmd {
+		case arqCmdAck:
+			if rtt := int32(a.now() - seg.ts); rtt >= 0 {
+				a.updateRTT(rtt)
+			}
+			a.parseAck(seg.seq)
+			if !acked || int32(seg.seq-maxAck) > 0 {
+				maxAck, acked = seg.seq, true
+			}
+		case arqCmdData:
+			if int32(seg.seq-a.rcvNxt) >= int32(a.wnd) {
+				continue // 超出接收窗口，等待重传
+			}
+			a.acks = append(a.acks, arqAck{seq: seg.seq, ts: seg.ts})
+			if int32(seg.seq-a.rcvNxt) >= 0 {
+				a.insertRcv(seg)
+			}
+		}
+	}
+	if acked {
+		for _, seg := range a.sndBuf {
+			if int32(seg.seq-maxAck) < 0 {
+				seg.fastack++
+			}
+		}
+	}
+	if a.config.Congestion && int32(a.sndUna-prevUna) > 0 && a.cwnd < a.wnd {
+		if a.cwnd < a.ssthresh {
+			a.cwnd++ // 慢启动
+		} else {
+			a.incr++ // 拥塞避免，每个窗口的数据被确认后加 1
+			if a.incr >= a.cwnd {
+				a.incr = 0
+				a.cwnd++
+			}
+		}
+	}
+	a.moveRcv()
+	a.flush()
+	a.schedule()
+	a.cond.Broadcast()
+}
+
+func (a *arq) updateRTT(rtt int32) {
+	if a.srtt == 0 {
+		a.srtt, a.rttvar = rtt, rtt/2
+	} else {
+		delta := rtt - a.srtt
+		if delta < 0 {
+			delta = -delta
+		}
+		a.rttvar = (3*a.rttvar + delta) / 4
+		a.srtt = (7*a.srtt + rtt) / 8
+	}
+	rto := uint32(a.srtt + max(int32(a.config.Interval.Milliseconds()), 4*a.rttvar))
+	a.rto = min(max(rto, uint32(a.config.RTOMin.Milliseconds())), uint32(a.config.RTOMax.Milliseconds()))
+}
+
+// parseUna 对端已经按序收到 una 之前的所有数据
+func (a *arq) parseUna(una uint32) {
+	i := 0
+	for i < len(a.sndBuf) && int32(a.sndBuf[i].seq-una) < 0 {
+		i++
+	}
+	if i > 0 {
+		a.sndBuf = a.sndBuf[i:]
+		a.shrink()
+	}
+}
+
+// parseAck 对端收到了 seq
+func (a *arq) parseAck(seq uint32) {
+	for i, seg := range a.sndBuf {
+		if seg.seq == seq {
+			a.sndBuf = append(a.sndBuf[:i], a.sndBuf[i+1:]...)
+			a.shrink()
+			return
+		} else if int32(seg.seq-seq) > 0 {
+			return
+		}
+	}
+}
+
+func (a *arq) shrink() {
+	if len(a.sndBuf) > 0 {
+		a.sndUna = a.sndBuf[0].seq
+	} else {
+		a.sndUna = a.sndNxt
+	}
+}
+
+// insertRcv 按序号插入乱序缓存，重复的数据丢弃
+func (a *arq) insertRcv(seg *arqSegment) {
+	i := len(a.rcvBuf)
+	for i > 0 && int32(a.rcvBuf[i-1].seq-seg.seq) > 0 {
+		i--
+	}
+	if i > 0 && a.rcvBuf[i-1].seq == seg.seq {
+		return
+	}
+	a.rcvBuf = append(a.rcvBuf, nil)
+	copy(a.rcvBuf[i+1:], a.rcvBuf[i:])
+	a.rcvBuf[i] = seg
+}
+
+// moveRcv 将按序到达的数据移入读取队列，读取队列满时停止，对端根据通告的窗口降低发送速度
+func (a *arq) moveRcv() {
+	i := 0
+	for i < len(a.rcvBuf) && a.rcvBuf[i].seq == a.rcvNxt && uint32(len(a.rcvQueue)) < a.wnd {
+		a.rcvQueue = append(a.rcvQueue, a.rcvBuf[i])
+		a.rcvNxt++
+		i++
+	}
+	if i > 0 {
+		a.rcvBuf = a.rcvBuf[i:]
+	}
+}
+
+// flush 发送确认、新数据和需要重传的数据，调用者需要持有锁
+func (a *arq) flush() {
+	if a.err != nil {
+		return
+	}
+	cur := a.now()
+	wnd := uint16(0)
+	if n := uint32(len(a.rcvQueue)); n < a.wnd {
+		wnd = uint16(a.wnd - n)
+	}
+	mtu := int(a.config.MTU)
+	a.buff = a.buff[:0]
+	write := func(seg *arqSegment) {
+		if len(a.buff) > 0 && len(a.buff)+arqHeadSize+len(seg.data) > mtu {
+			a.write()
+		}
+		a.buff = seg.encode(a.buff)
+	}
+	for _, ack := range a.acks {
+		write(&arqSegment{conv: a.conv, cmd: arqCmdAck, wnd: wnd, ts: ack.ts, seq: ack.seq, una: a.rcvNxt})
+	}
+	a.acks = a.acks[:0]
+
+	cwnd := min(a.wnd, a.rmtWnd)
+	if a.config.Congestion {
+		cwnd = min(cwnd, a.cwnd)
+	}
+	if cwnd == 0 && len(a.sndBuf) == 0 {
+		cwnd = 1 // 对端窗口为 0 时发送一个数据包探测窗口
+	}
+	queued := len(a.sndQueue)
+	for len(a.sndQueue) > 0 && a.sndNxt-a.sndUna < cwnd {
+		seg := a.sndQueue[0]
+		a.sndQueue = a.sndQueue[1:]
+		seg.seq = a.sndNxt
+		seg.rto = a.rto
+		a.sndNxt++
+		a.sndBuf = append(a.sndBuf, seg)
+	}
+	if len(a.sndQueue) < queued {
+		a.cond.Broadcast()
+	}
+
+	var lost, fast bool
+	for _, seg := range a.sndBuf {
+		switch {
+		case seg.xmit == 0:
+			seg.resendAt = cur + seg.rto
+		case int32(cur-seg.resendAt) >= 0:
+			seg.rto = min(seg.rto*2, uint32(a.config.RTOMax.Milliseconds()))
+			seg.resendAt = cur + seg.rto
+			lost = true
+		case a.config.FastResend > 0 && seg.fastack >= a.config.FastResend:
+			seg.fastack = 0
+			seg.resendAt = cur + seg.rto
+			fast = true
+		default:
+			continue
+		}
+		seg.xmit++
+		seg.ts, seg.wnd, seg.una = cur, wnd, a.rcvNxt
+		write(seg)
+		if seg.xmit > a.config.MaxRetransmit+1 {
+			a.fail(ErrDeadLink)
+			return
+		}
+	}
+	if len(a.buff) > 0 {
+		a.write()
+	}
+	if a.config.Congestion {
+		if fast {
+			a.ssthresh = max((a.sndNxt-a.sndUna)/2, 2)
+			a.cwnd = a.ssthresh + uint32(a.config.FastResend)
+		}
+		if lost {
+			a.ssthresh = max(a.cwnd/2, 2)
+			a.cwnd = 1
+		}
+	}
+}
+
+func (a *arq) write() {
+	if err := a.output(a.buff); err != nil {
+		a.fail(err)
+	}
+	a.buff = a.buff[:0]
+}
@@ -0,0 +1,215 @@
+package udp
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hwcer/cosnet/message"
+)
+
+// lossyPair 创建一对可靠传输，数据包按 loss 比例丢弃，并随机延迟造成乱序
+func lossyPair(t *testing.T, config *Config, loss float64) (*arq, *arq) {
+	var mutex sync.Mutex
+	var a, b *arq
+	link := func(peer **arq) func([]byte) error {
+		return func(data []byte) error {
+			mutex.Lock()
+			drop := rand.Float64() < loss
+			mutex.Unlock()
+			if drop {
+				return nil
+			}
+			cp := append([]byte(nil), data...)
+			time.AfterFunc(time.Duration(rand.IntN(5))*time.Millisecond, func() {
+				(*peer).input(cp)
+			})
+			return nil
+		}
+	}
+	mutex.Lock()
+	a = newArq(1, config, link(&b))
+	b = newArq(1, config, link(&a))
+	mutex.Unlock()
+	t.Cleanup(func() {
+		a.close(net.ErrClosed)
+		b.close(net.ErrClosed)
+	})
+	return a, b
+}
+
+func TestReliable(t *testing.T) {
+	for _, congestion := range []bool{false, true} {
+		config := (&Config{Interval: 5 * time.Millisecond, RTOMin: 10 * time.Millisecond, Window: 32, FastResend: 2, Congestion: congestion}).fallback()
+		a, b := lossyPair(t, config, 0.2)
+		const count = 200
+		go func() {
+			for i := 0; i < count; i++ {
+				size := 1 + rand.IntN(5000) // 超过 MTU 时分片
+				data := []byte(fmt.Sprintf("%04d:", i) + strings.Repeat("x", size))
+				if err := a.send(data); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		for i := 0; i < count; i++ {
+			done := make(chan []byte, 1)
+			go func() {
+				data, _ := b.recv()
+				done <- data
+			}()
+			select {
+			case data := <-done:
+				if !bytes.HasPrefix(data, []byte(fmt.Sprintf("%04d:", i))) {
+					t.Fatalf("congestion %v message %d out of order:%q", congestion, i, data[:min(len(data), 8)])
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatalf("congestion %v message %d timeout", congestion, i)
+			}
+		}
+	}
+}
+
+func TestReliableDeadLink(t *testing.T) {
+	config := (&Config{Interval: time.Millisecond, RTOMin: time.Millisecond, RTOMax: 5 * time.Millisecond, MaxRetransmit: 3}).fallback()
+	a, _ := lossyPair(t, config, 1)
+	if err := a.send([]byte("lost")); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.recv()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != ErrDeadLink {
+			t.Fatalf("expected dead link, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead link not detected")
+	}
+}
+
+func TestReliableListener(t *testing.T) {
+	config := &Config{ConnChanSize: 10, Reliable: true, Unreliable: func(msg message.Message) bool {
+		p, _, _ := msg.Path()
+		return strings.HasPrefix(p, "/pos/")
+	}}
+	ln, err := NewWithConfig("udp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	client, err := DialWithConfig("udp", ln.Addr().String(), time.Second, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	send := func(path string, body []byte) {
+		m := message.Require()
+		defer message.Release(m)
+		if err := m.Marshal(message.MagicNumberPathJson, 0, 0, path, body); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.WriteMessage(nil, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send("/pos/move", []byte("1,2"))
+	big := bytes.Repeat([]byte("y"), 10000)
+	for i := 0; i < 20; i++ {
+		send(fmt.Sprintf("/chat/%d", i), big)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for len(paths) < 21 {
+		m := message.Require()
+		if err = conn.ReadMessage(nil, m); err != nil {
+			t.Fatal(err)
+		}
+		p, _, _ := m.Path()
+		if strings.HasPrefix(p, "/chat/") && !bytes.Equal(m.Body(), big) {
+			t.Fatalf("%v body size %d", p, len(m.Body()))
+		}
+		paths = append(paths, p)
+	}
+	var chat []string
+	for _, p := range paths {
+		if strings.HasPrefix(p, "/chat/") {
+			chat = append(chat, p)
+		}
+	}
+	for i, p := range chat {
+		if p != fmt.Sprintf("/chat/%d", i) {
+			t.Fatalf("reliable messages out of order:%v", chat)
+		}
+	}
+	if len(chat) != 20 {
+		t.Fatalf("paths %v", paths)
+	}
+}
+
+func TestReliableIdle(t *testing.T) {
+	config := (&Config{Interval: time.Millisecond}).fallback()
+	a, b := lossyPair(t, config, 0)
+	idle := func(x *arq) bool {
+		x.mutex.Lock()
+		defer x.mutex.Unlock()
+		return x.idle
+	}
+	if !idle(a) || !idle(b) {
+		t.Fatal("new arq not idle")
+	}
+	if err := a.send([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := b.recv(); err != nil || string(data) != "hello" {
+		t.Fatalf("recv %q %v", data, err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !idle(a) || !idle(b) {
+		if time.Now().After(deadline) {
+			t.Fatal("ticker not stopped after data acknowledged")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReliableListenerStray(t *testing.T) {
+	ln, err := NewWithConfig("udp", "127.0.0.1:0", &Config{ConnChanSize: 10, Reliable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	c, err := net.Dial("udp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = c.Close() }()
+	ack := &arqSegment{conv: 7, cmd: arqCmdAck, seq: 3}
+	data := &arqSegment{conv: 7, cmd: arqCmdData, seq: 5, data: []byte("late")}
+	for _, seg := range []*arqSegment{ack, data} {
+		if _, err = c.Write(seg.encode(nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	l := ln.(*Listener)
+	l.mu.Lock()
+	n := len(l.conns)
+	l.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("stray packets created %d conns", n)
+	}
+}
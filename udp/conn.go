@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/hwcer/cosnet/listener"
@@ -19,6 +20,8 @@ type Conn struct {
 	head    []byte      // 用于存储消息头
 	ln      *Listener   // 引用监听器，用于在关闭时移除自身
 	key     string      // 用于在监听器的conns map中标识自身
+	arq     *arq        // 可靠模式下的可靠传输，nil 表示不可靠模式
+	once    sync.Once   // 保证只关闭一次
 }
 
 // Read 从连接中读取数据
@@ -30,7 +33,7 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 func (c *Conn) Write(b []byte) (n int, err error) {
 	n, err = c.conn.WriteToUDP(b, c.addr)
 	if err != nil {
-		c.ln.removeConn(c.key) // 出错时从活跃连接列表中移除
+		c.ln.removeConn(c.key, c) // 出错时从活跃连接列表中移除
 	}
 	return n, err
 }
@@ -38,9 +41,15 @@ func (c *Conn) Write(b []byte) (n int, err error) {
 // Close 关闭连接
 func (c *Conn) Close() error {
 	// 从活跃连接列表中移除
-	c.ln.removeConn(c.key)
-	// 关闭msgChan通道，避免资源泄漏
-	close(c.msgChan)
+	c.ln.removeConn(c.key, c)
+	c.once.Do(func() {
+		if c.arq != nil {
+			c.arq.close(net.ErrClosed)
+			return
+		}
+		// 关闭msgChan通道，避免资源泄漏
+		close(c.msgChan)
+	})
 	// UDP是无连接的，所以这里不需要关闭底层连接
 	// 底层连接由Listener管理
 	return nil
@@ -78,10 +87,18 @@ func (c *Conn) ReadMessage(_ listener.Socket, msg message.Message) error {
 		c.head = message.Options.Head()
 	}
 
-	// 从msgChan中读取数据包
-	b, ok := <-c.msgChan
-	if !ok {
-		return io.EOF
+	var b []byte
+	if c.arq != nil {
+		var err error
+		if b, err = c.arq.recv(); err != nil {
+			return err
+		}
+	} else {
+		// 从msgChan中读取数据包
+		var ok bool
+		if b, ok = <-c.msgChan; !ok {
+			return io.EOF
+		}
 	}
 
 	// 检查数据包长度是否足够
@@ -108,6 +125,9 @@ func (c *Conn) WriteMessage(_ listener.Socket, msg message.Message) error {
 		return err
 	}
 
+	if c.arq != nil {
+		return c.arq.sendMessage(msg, buffer.Bytes())
+	}
 	// 发送消息
 	_, err = c.conn.WriteToUDP(buffer.Bytes(), c.addr)
 	return err
@@ -0,0 +1,106 @@
+package udp
+
+import (
+	"bytes"
+	"net"
+	"time"
+
+	"github.com/hwcer/cosnet/listener"
+	"github.com/hwcer/cosnet/message"
+)
+
+// Dial 连接 UDP 服务器，返回客户端模式的连接。
+// 参数:
+//   - network: "udp", "udp4", "udp6"
+//   - address: 服务器地址
+//   - timeout: 地址解析超时时间
+func Dial(network, address string, timeout time.Duration) (listener.Conn, error) {
+	return DialWithConfig(network, address, timeout, nil)
+}
+
+// DialWithConfig 使用指定配置连接 UDP 服务器，config 为 nil 时使用 Options，可靠模式需要与服务器一致。
+func DialWithConfig(network, address string, timeout time.Duration, config *Config) (listener.Conn, error) {
+	if config == nil {
+		config = &Options
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	c := &ClientConn{UDPConn: conn.(*net.UDPConn)}
+	if config.Reliable {
+		c.arq = newArq(0, config.fallback(), func(b []byte) error {
+			_, err := c.UDPConn.Write(b)
+			return err
+		})
+		go c.readLoop()
+	}
+	return c, nil
+}
+
+// ClientConn 客户端模式的 UDP 连接，独占一个本地端口，只与一个服务器通信。
+type ClientConn struct {
+	*net.UDPConn
+	buff []byte
+	arq  *arq // 可靠模式下的可靠传输，nil 表示不可靠模式
+}
+
+// readLoop 可靠模式下读取数据包交给可靠传输
+func (c *ClientConn) readLoop() {
+	buffer := make([]byte, 65535) // UDP最大数据包大小
+	for {
+		n, err := c.UDPConn.Read(buffer)
+		if err != nil {
+			c.arq.close(err)
+			return
+		}
+		// 复制数据到新的切片，避免缓冲区被覆盖
+		data := make([]byte, n)
+		copy(data, buffer[:n])
+		c.arq.input(data)
+	}
+}
+
+// ReadMessage 实现cosnet的消息读取接口
+func (c *ClientConn) ReadMessage(_ listener.Socket, msg message.Message) error {
+	if c.arq != nil {
+		data, err := c.arq.recv()
+		if err != nil {
+			return err
+		}
+		return msg.Reset(data)
+	}
+	if c.buff == nil {
+		c.buff = make([]byte, 65535) // UDP最大数据包大小
+	}
+	n, err := c.UDPConn.Read(c.buff)
+	if err != nil {
+		return err
+	}
+	// 复制数据到新的切片，避免缓冲区被覆盖
+	data := make([]byte, n)
+	copy(data, c.buff[:n])
+	return msg.Reset(data)
+}
+
+// WriteMessage 实现cosnet的消息写入接口
+func (c *ClientConn) WriteMessage(_ listener.Socket, msg message.Message) error {
+	buffer := bytes.NewBuffer(nil)
+	if _, err := msg.Bytes(buffer, true); err != nil {
+		return err
+	}
+	if c.arq != nil {
+		return c.arq.sendMessage(msg, buffer.Bytes())
+	}
+	_, err := c.UDPConn.Write(buffer.Bytes())
+	return err
+}
+
+// Close 关闭连接，可靠模式下未确认的数据被丢弃
+func (c *ClientConn) Close() error {
+	if c.arq != nil {
+		c.arq.close(net.ErrClosed)
+	}
+	return c.UDPConn.Close()
+}
@@ -18,10 +18,22 @@ type Listener struct {
 	network string
 	conns   map[string]*Conn // 用于跟踪活跃的Conn对象
 	mu      sync.Mutex       // 用于保护conns map的并发访问
+	config  *Config
 }
 
 // New 创建一个新的udp监听器
 func New(network, address string) (listener.Listener, error) {
+	return NewWithConfig(network, address, nil)
+}
+
+// NewWithConfig 使用指定配置创建udp监听器，config 为 nil 时使用 Options
+func NewWithConfig(network, address string, config *Config) (listener.Listener, error) {
+	if config == nil {
+		config = &Options
+	}
+	if config.Reliable {
+		config = config.fallback()
+	}
 	addr, err := net.ResolveUDPAddr(network, address)
 	if err != nil {
 		return nil, err
@@ -32,10 +44,11 @@ func New(network, address string) (listener.Listener, error) {
 	}
 	result := &Listener{
 		ln:      ln,
-		connCh:  make(chan *Conn, Options.ConnChanSize), // 使用配置的通道大小
+		connCh:  make(chan *Conn, config.ConnChanSize), // 使用配置的通道大小
 		addr:    ln.LocalAddr(),
 		network: network,
 		conns:   make(map[string]*Conn),
+		config:  config,
 	}
 	scc.GO(result.readLoop)
 	return result, nil
@@ -52,14 +65,20 @@ func (ln *Listener) Accept() (listener.Conn, error) {
 
 // Close 关闭监听器
 func (ln *Listener) Close() error {
-	close(ln.connCh)
-	// 关闭所有活跃的Conn对象，使用写锁
+	// conns 为 nil 表示已关闭，readLoop 持有锁时检查，避免向已关闭的通道发送
 	ln.mu.Lock()
-	for _, conn := range ln.conns {
-		conn.Close()
+	conns := ln.conns
+	if conns == nil {
+		ln.mu.Unlock()
+		return nil
 	}
 	ln.conns = nil
+	close(ln.connCh)
 	ln.mu.Unlock()
+	// 关闭所有活跃的Conn对象，Conn.Close 会调用 removeConn，需要在锁外关闭
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
 	return ln.ln.Close()
 }
 
@@ -68,20 +87,30 @@ func (ln *Listener) Addr() net.Addr {
 	return ln.addr
 }
 
-// newConn 创建一个新的UDP连接
+// newConn 创建一个新的UDP连接，conv 为可靠模式下客户端的会话编号
 // 注意：调用此方法前，必须已经持有ln.mu互斥锁
-func (ln *Listener) newConn(conn *net.UDPConn, addr *net.UDPAddr, key string) (r *Conn, err error) {
+func (ln *Listener) newConn(conn *net.UDPConn, addr *net.UDPAddr, key string, conv uint32) (r *Conn, err error) {
 	r = &Conn{
-		conn:    conn,
-		addr:    addr,
-		msgChan: make(chan []byte, Options.MsgChanSize), // 使用配置的通道大小
-		ln:      ln,
-		key:     key,
+		conn: conn,
+		addr: addr,
+		ln:   ln,
+		key:  key,
+	}
+	if ln.config.Reliable {
+		r.arq = newArq(conv, ln.config, func(b []byte) error {
+			_, err := conn.WriteToUDP(b, addr)
+			return err
+		})
+	} else {
+		r.msgChan = make(chan []byte, ln.config.MsgChanSize) // 使用配置的通道大小
 	}
 	// 发送到通道
 	select {
 	case ln.connCh <- r:
 	default:
+		if r.arq != nil {
+			r.arq.close(net.ErrClosed)
+		}
 		return nil, errors.New("udp listener conn channel full, drop conn")
 		// 通道已满，丢弃该连接
 	}
@@ -99,40 +128,77 @@ func (ln *Listener) readLoop() {
 			break
 		}
 		if n > 0 {
+			// 可靠模式下丢弃无法识别的数据包
+			var conv uint32
+			if ln.config.Reliable {
+				var ok bool
+				if conv, ok = arqConv(buffer[:n]); !ok {
+					continue
+				}
+			}
 			// 生成端点的唯一标识
 			addrKey := addr.String()
+			// 复制数据到新的切片，避免缓冲区被覆盖
+			data := make([]byte, n)
+			copy(data, buffer[:n])
 			// 检查是否已存在该端点的Conn对象
 			ln.mu.Lock()
+			if ln.conns == nil {
+				ln.mu.Unlock()
+				return // 监听器已关闭
+			}
 			conn, exists := ln.conns[addrKey]
+			if exists && conn.arq != nil && conn.arq.conv != conv {
+				if !arqFirst(data) {
+					ln.mu.Unlock()
+					continue // 旧会话延迟到达的数据包
+				}
+				// 客户端使用相同的地址重新连接，关闭旧连接
+				ln.mu.Unlock()
+				_ = conn.Close()
+				ln.mu.Lock()
+				if ln.conns == nil {
+					ln.mu.Unlock()
+					return
+				}
+				exists = false
+			}
+			if !exists && ln.config.Reliable && !arqFirst(data) {
+				ln.mu.Unlock()
+				continue // 不是新会话的第一个数据包，例如已经结束的会话延迟到达的确认
+			}
 			if !exists {
 				// 创建一个新的UDP连接
-				conn, err = ln.newConn(ln.ln, addr, addrKey)
+				conn, err = ln.newConn(ln.ln, addr, addrKey, conv)
 				if err != nil {
 					ln.mu.Unlock()
 					continue
 				}
 			}
-			ln.mu.Unlock()
-
-			// 复制数据到新的切片，避免缓冲区被覆盖
-			data := make([]byte, n)
-			copy(data, buffer[:n])
+			if conn.arq != nil {
+				ln.mu.Unlock()
+				conn.arq.input(data)
+				continue
+			}
 
-			// 将数据包发送到Conn对象的msgChan中
+			// 将数据包发送到Conn对象的msgChan中，持有锁发送，Conn.Close 从 conns 中移除后才关闭 msgChan
 			select {
 			case conn.msgChan <- data:
 			default:
 				logger.Alert("udp conn %s msg channel full, drop msg", conn.key)
 				// 通道已满，丢弃该数据包
 			}
+			ln.mu.Unlock()
 		}
 	}
 }
 
-// removeConn 从活跃连接列表中移除Conn对象
-func (ln *Listener) removeConn(key string) {
+// removeConn 从活跃连接列表中移除Conn对象，key 已经对应新的Conn时不移除
+func (ln *Listener) removeConn(key string, conn *Conn) {
 	// 移除操作需要写锁
 	ln.mu.Lock()
-	delete(ln.conns, key)
+	if ln.conns[key] == conn {
+		delete(ln.conns, key)
+	}
 	ln.mu.Unlock()
 }
@@ -1,12 +1,77 @@
 package udp
 
-// Options UDP模块配置选项
-var Options = struct {
+import (
+	"time"
+
+	"github.com/hwcer/cosnet/message"
+)
+
+// Config UDP模块配置，可以通过 NewWithConfig 为每个监听器单独设置
+type Config struct {
 	// ConnChanSize 连接通道缓存大小
 	ConnChanSize int32
-	// MsgChanSize 消息通道缓存大小
+	// MsgChanSize 消息通道缓存大小，可靠模式下为不可靠通道的接收缓存大小
 	MsgChanSize int32
-}{
-	ConnChanSize: 100, // 连接通道缓存 100 条消息
-	MsgChanSize:  100, // 消息通道缓存 100 条消息
+
+	// Reliable 是否启用可靠传输（ARQ：序号、确认、重传、窗口流控），服务器和客户端需要一致
+	Reliable bool
+	// Unreliable 可靠模式下仍然使用不可靠通道发送的消息，例如位置同步，nil 表示全部可靠发送
+	Unreliable func(msg message.Message) bool
+	// MTU 可靠模式下单个数据包的最大字节数，消息超过时分片发送
+	MTU int32
+	// Window 可靠模式下发送和接收窗口大小，单位为数据包
+	Window int32
+	// Interval 可靠模式下重传检查和窗口推进的间隔
+	Interval time.Duration
+	// RTOMin 可靠模式下重传超时的最小值
+	RTOMin time.Duration
+	// RTOMax 可靠模式下重传超时的最大值，超时重传按 2 倍退避直到该值
+	RTOMax time.Duration
+	// FastResend 可靠模式下收到多少个跨越该包的确认后立即重传，0 表示不启用
+	FastResend int32
+	// MaxRetransmit 可靠模式下单个数据包的最大重传次数，超过后认为连接已断开
+	MaxRetransmit int32
+	// Congestion 可靠模式下是否启用拥塞控制（慢启动、拥塞避免、丢包减窗），关闭时只受窗口限制，延迟更低
+	Congestion bool
+}
+
+// Options UDP模块默认配置选项
+var Options = Config{
+	ConnChanSize:  100,                   // 连接通道缓存 100 条消息
+	MsgChanSize:   100,                   // 消息通道缓存 100 条消息
+	MTU:           1400,                  // 单个数据包不超过 1400 字节，避免 IP 分片
+	Window:        128,                   // 窗口 128 个数据包
+	Interval:      10 * time.Millisecond, // 每 10 毫秒检查一次重传
+	RTOMin:        30 * time.Millisecond, // 重传超时不低于 30 毫秒
+	RTOMax:        5 * time.Second,       // 重传超时不超过 5 秒
+	FastResend:    2,                     // 2 个跨越确认后快速重传
+	MaxRetransmit: 20,                    // 单个数据包重传 20 次后断开
+	Congestion:    false,                 // 默认不启用拥塞控制
+}
+
+// fallback 可靠模式的零值字段使用 Options
+func (c *Config) fallback() *Config {
+	r := *c
+	if r.MsgChanSize <= 0 {
+		r.MsgChanSize = Options.MsgChanSize
+	}
+	if r.MTU <= 0 {
+		r.MTU = Options.MTU
+	}
+	if r.Window <= 0 {
+		r.Window = Options.Window
+	}
+	if r.Interval <= 0 {
+		r.Interval = Options.Interval
+	}
+	if r.RTOMin <= 0 {
+		r.RTOMin = Options.RTOMin
+	}
+	if r.RTOMax <= 0 {
+		r.RTOMax = Options.RTOMax
+	}
+	if r.MaxRetransmit <= 0 {
+		r.MaxRetransmit = Options.MaxRetransmit
+	}
+	return &r
 }
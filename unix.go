@@ -0,0 +1,13 @@
+package cosnet
+
+import "github.com/hwcer/cosnet/listener"
+
+// PeerCredentials 获取 Unix domain socket 对端进程的身份（uid/gid/pid），可以用来限制只允许特定用户的本机工具连接。
+// 需要开启 tcp.Config.UnixCredentials，仅 Linux 支持。
+// 返回值: 不是 Unix domain socket 连接或者没有读取到时第二个返回值为 false。
+func (sock *Socket) PeerCredentials() (listener.Credentials, bool) {
+	if c, ok := sock.Conn().(listener.CredentialsConn); ok {
+		return c.PeerCredentials()
+	}
+	return listener.Credentials{}, false
+}
@@ -2,6 +2,7 @@ package wss
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"io"
 	"log"
@@ -18,10 +19,33 @@ func NewConn(c *websocket.Conn) *Conn {
 	return &Conn{Conn: c}
 }
 
+// NewConnWithConfig 使用指定配置创建连接，config 为 nil 时使用 Options
+func NewConnWithConfig(c *websocket.Conn, config *Config) *Conn {
+	return &Conn{Conn: c, config: config}
+}
+
 // Conn net.Conn
 type Conn struct {
 	*websocket.Conn
-	buff *bytes.Buffer
+	buff   *bytes.Buffer
+	config *Config
+	remote net.Addr // 代理提供的客户端地址
+}
+
+// RemoteAddr 返回客户端地址，经过受信任的代理时为代理提供的地址。
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// TLSConnectionState 返回 TLS 连接状态，实现 listener.TLSConn 接口。
+func (c *Conn) TLSConnectionState() (tls.ConnectionState, bool) {
+	if tc, ok := c.Conn.NetConn().(*tls.Conn); ok {
+		return tc.ConnectionState(), true
+	}
+	return tls.ConnectionState{}, false
 }
 
 // Read 实现 net.Conn 接口,不推荐使用
@@ -64,7 +88,7 @@ func (c *Conn) ReadMessage(socket listener.Socket, msg message.Message) error {
 	if len(b) == 0 {
 		return io.EOF
 	}
-	if err = Options.Transform.ReadMessage(socket, msg, b); err != nil {
+	if err = c.config.transform().ReadMessage(socket, msg, b); err != nil {
 		return err
 	}
 	return nil
@@ -79,7 +103,7 @@ func (c *Conn) WriteMessage(socket listener.Socket, msg message.Message) error {
 	}()
 
 	var err error
-	if _, err = Options.Transform.WriteMessage(socket, msg, c.buff); err != nil {
+	if _, err = c.config.transform().WriteMessage(socket, msg, c.buff); err != nil {
 		logger.Error(err)
 		return err
 	}
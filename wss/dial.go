@@ -0,0 +1,36 @@
+package wss
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hwcer/cosnet/listener"
+)
+
+// Dial 连接 WebSocket 服务器，返回客户端模式的连接。
+// 参数:
+//   - url: 服务器地址，例如 ws://127.0.0.1:8080/ws、wss://example.com/ws
+//   - timeout: 握手超时时间
+//   - tlsConfig: TLS 配置，仅 wss 使用，为 nil 时使用默认配置
+//   - header: 握手时附加的请求头，可选
+//   - config: 可选，连接使用的配置，默认使用 Options
+func Dial(url string, timeout time.Duration, tlsConfig *tls.Config, header http.Header, config ...*Config) (listener.Conn, error) {
+	c := &Options
+	if len(config) > 0 && config[0] != nil {
+		c = config[0]
+	}
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: timeout,
+		TLSClientConfig:  tlsConfig,
+		ReadBufferSize:   c.Upgrader.ReadBufferSize,
+		WriteBufferSize:  c.Upgrader.WriteBufferSize,
+	}
+	conn, _, err := dialer.Dial(url, header)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnWithConfig(conn, c), nil
+}
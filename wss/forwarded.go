@@ -0,0 +1,78 @@
+package wss
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+// forwardedFor 按从远到近的顺序取出 Forwarded（优先）或 X-Forwarded-For 中记录的地址
+func forwardedFor(r *http.Request) []string {
+	var list []string
+	for _, h := range r.Header.Values("Forwarded") {
+		for _, elem := range strings.Split(h, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(k, "for") {
+					list = append(list, strings.Trim(v, `"`))
+				}
+			}
+		}
+	}
+	if len(list) > 0 {
+		return list
+	}
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		for _, v := range strings.Split(h, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+	}
+	return list
+}
+
+// parseForwardedAddr 解析 "1.2.3.4"、"1.2.3.4:80"、"[2001:db8::1]:80"、"2001:db8::1" 格式的地址
+func parseForwardedAddr(s string) *net.TCPAddr {
+	if ip := net.ParseIP(strings.Trim(s, "[]")); ip != nil {
+		return &net.TCPAddr{IP: ip}
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	p, _ := strconv.Atoi(port)
+	return &net.TCPAddr{IP: ip, Port: p}
+}
+
+// remoteAddr 获取真实的客户端地址：请求来自受信任的代理时，从右向左跳过受信任的代理，第一个不受信任的地址即客户端地址
+// 返回 nil 表示使用连接地址
+func remoteAddr(r *http.Request, trusted listener.Trusted) net.Addr {
+	peer := parseForwardedAddr(r.RemoteAddr)
+	if peer == nil || !trusted.ContainsIP(peer.IP) {
+		return nil
+	}
+	list := forwardedFor(r)
+	var addr *net.TCPAddr
+	for i := len(list) - 1; i >= 0; i-- {
+		a := parseForwardedAddr(list[i])
+		if a == nil {
+			break // unknown 或混淆的标识符，无法继续追溯
+		}
+		addr = a
+		if !trusted.ContainsIP(a.IP) {
+			break
+		}
+	}
+	if addr == nil {
+		return nil
+	}
+	return addr
+}
@@ -0,0 +1,53 @@
+package wss
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hwcer/cosnet/listener"
+)
+
+func TestRemoteAddr(t *testing.T) {
+	trusted, err := listener.ParseTrusted([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		peer   string
+		header map[string]string
+		want   string
+	}{
+		{"10.0.0.2:5000", map[string]string{"X-Forwarded-For": "1.1.1.1, 203.0.113.9, 192.168.1.1"}, "203.0.113.9:0"},
+		{"10.0.0.2:5000", map[string]string{"Forwarded": `for=198.51.100.4;proto=https, for="[2001:db8::1]:4711"`}, "[2001:db8::1]:4711"},
+		{"10.0.0.2:5000", map[string]string{"Forwarded": "for=198.51.100.4", "X-Forwarded-For": "1.1.1.1"}, "198.51.100.4:0"},
+		{"203.0.113.1:5000", map[string]string{"X-Forwarded-For": "1.1.1.1"}, ""}, // 不受信任的代理
+		{"10.0.0.2:5000", nil, ""},
+	}
+	for _, c := range cases {
+		r := &http.Request{RemoteAddr: c.peer, Header: http.Header{}}
+		for k, v := range c.header {
+			r.Header.Set(k, v)
+		}
+		got := ""
+		if addr := remoteAddr(r, trusted); addr != nil {
+			got = addr.String()
+		}
+		if got != c.want {
+			t.Errorf("%v %v: got %q, want %q", c.peer, c.header, got, c.want)
+		}
+	}
+}
+
+func TestForwardedTrusted(t *testing.T) {
+	r := &http.Request{RemoteAddr: "10.0.0.2:5000", Header: http.Header{"X-Forwarded-For": {"1.1.1.1"}}}
+	if addr := remoteAddr(r, nil); addr != nil {
+		t.Fatalf("empty trusted list accepted forwarded address %v", addr)
+	}
+	if _, err := NewWithConfig("ws", "127.0.0.1:0", &Config{Forwarded: true}); !errors.Is(err, listener.ErrTrustedRequired) {
+		t.Fatalf("Forwarded without ForwardedTrusted:%v", err)
+	}
+	if _, err := NewWithConfig("ws", "127.0.0.1:0", &Config{Forwarded: true, ForwardedTrusted: []string{"10.0.0.300"}}); err == nil {
+		t.Fatal("invalid ForwardedTrusted accepted")
+	}
+}
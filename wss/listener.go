@@ -3,11 +3,11 @@ package wss
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/hwcer/cosgo/scc"
 	"github.com/hwcer/cosnet/listener"
 	"github.com/hwcer/logger"
@@ -17,6 +17,18 @@ import (
 // 仅仅提供给cosnet快速创建 wss 服务器
 // network : "ws", "wss", "wss4", "wss5", "wss6"
 func New(network, address string, tlsConfig ...*tls.Config) (listener.Listener, error) {
+	return NewWithConfig(network, address, nil, tlsConfig...)
+}
+
+// NewWithConfig 使用指定配置创建wss监听器，config 为 nil 时使用 Options
+func NewWithConfig(network, address string, config *Config, tlsConfig ...*tls.Config) (listener.Listener, error) {
+	if config == nil {
+		config = &Options
+	}
+	trusted, err := parseTrusted(config)
+	if err != nil {
+		return nil, err
+	}
 	srv := &http.Server{
 		Addr:              address,
 		ReadHeaderTimeout: 3 * time.Second,
@@ -30,9 +42,9 @@ func New(network, address string, tlsConfig ...*tls.Config) (listener.Listener,
 		return nil, errors.New("TLS configuration is required for wss network type")
 	}
 
-	ln := NewListener(srv, "")
+	ln := newListener(srv, "", config, trusted)
 	//启动服务
-	err := scc.Timeout(time.Second, func() error {
+	err = scc.Timeout(time.Second, func() error {
 		if srv.TLSConfig != nil {
 			return srv.ListenAndServeTLS("", "")
 		}
@@ -44,32 +56,64 @@ func New(network, address string, tlsConfig ...*tls.Config) (listener.Listener,
 	return ln, err
 }
 
+// NewListener 使用 Options 创建监听器，ForwardedTrusted 无效时记录日志并忽略代理提供的请求头
 func NewListener(srv *http.Server, route string) *Listener {
+	ln, err := NewListenerWithConfig(srv, route, nil)
+	if err != nil {
+		logger.Alert("wss forwarded headers ignored:%v", err)
+		ln = newListener(srv, route, &Options, nil)
+	}
+	return ln
+}
+
+// NewListenerWithConfig 使用指定配置创建监听器，config 为 nil 时使用 Options
+// 返回值: 开启 Forwarded 但 ForwardedTrusted 为空或无效时返回错误
+func NewListenerWithConfig(srv *http.Server, route string, config *Config) (*Listener, error) {
+	if config == nil {
+		config = &Options
+	}
+	trusted, err := parseTrusted(config)
+	if err != nil {
+		return nil, err
+	}
+	return newListener(srv, route, config, trusted), nil
+}
+
+func newListener(srv *http.Server, route string, config *Config, trusted listener.Trusted) *Listener {
 	ln := &Listener{
 		route:    route,
 		server:   srv,
-		connChan: make(chan *websocket.Conn, Options.ConnChanSize), // 使用配置的通道大小
+		config:   config,
+		trusted:  trusted,
+		connChan: make(chan *Conn, config.ConnChanSize), // 使用配置的通道大小
 	}
 	srv.Handler = ln
 	return ln
 }
 
+// parseTrusted 解析 ForwardedTrusted，开启 Forwarded 时不能为空
+func parseTrusted(config *Config) (listener.Trusted, error) {
+	if !config.Forwarded {
+		return nil, nil
+	}
+	if len(config.ForwardedTrusted) == 0 {
+		return nil, fmt.Errorf("wss ForwardedTrusted:%w", listener.ErrTrustedRequired)
+	}
+	return listener.ParseTrusted(config.ForwardedTrusted)
+}
+
 // Listener 实现listener.Listener接口
 type Listener struct {
-	err      error
 	route    string
 	server   *http.Server
-	connChan chan *websocket.Conn
+	config   *Config
+	trusted  listener.Trusted
+	connChan chan *Conn
 }
 
 // Accept 等待并返回下一个连接到监听器
 func (ln *Listener) Accept() (listener.Conn, error) {
-	if ln.err != nil {
-		return nil, ln.err
-	}
-	conn := <-ln.connChan
-	wssConn := NewConn(conn)
-	return wssConn, nil
+	return <-ln.connChan, nil
 }
 
 // Close 关闭监听器
@@ -109,11 +153,15 @@ func (ln *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	var header = map[string][]string{"Sec-WebSocket-Protocol": {r.Header.Get("Sec-WebSocket-Protocol")}}
 
-	conn, err := Options.Upgrader.Upgrade(w, r, header)
+	c, err := ln.config.upgrader().Upgrade(w, r, header)
 	if err != nil {
 		ln.HTTPErrorHandler(w, r, err)
 		return
 	}
+	conn := NewConnWithConfig(c, ln.config)
+	if ln.config.Forwarded {
+		conn.remote = remoteAddr(r, ln.trusted)
+	}
 	// 使用非阻塞的方式发送连接到通道
 	select {
 	case ln.connChan <- conn:
@@ -9,13 +9,20 @@ import (
 	"github.com/hwcer/cosnet/message"
 )
 
-// Options WSS模块配置选项
-var Options = struct {
+// Config WSS模块配置，可以通过 NewWithConfig 为每个监听器单独设置
+type Config struct {
 	Origin       []string
 	ConnChanSize int32
 	Upgrader     websocket.Upgrader
 	Transform    transform
-}{
+	// Forwarded 是否使用受信任代理提供的 Forwarded、X-Forwarded-For 请求头作为客户端地址
+	Forwarded bool
+	// ForwardedTrusted 受信任的代理地址（IP 或 CIDR），开启 Forwarded 时必须设置，否则创建监听器失败
+	ForwardedTrusted []string
+}
+
+// Options WSS模块默认配置选项
+var Options = Config{
 	Origin:       []string{},                                                      // 默认允许所有来源
 	ConnChanSize: 100,                                                             // 连接通道缓存 100 条消息
 	Upgrader:     websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}, // 默认配置，允许所有来源
@@ -27,10 +34,15 @@ func init() {
 }
 
 func AccessControlAllow(r *http.Request) bool {
-	if len(Options.Origin) == 0 {
+	return Options.AccessControlAllow(r)
+}
+
+// AccessControlAllow 按 Origin 检查是否允许连接
+func (c *Config) AccessControlAllow(r *http.Request) bool {
+	if len(c.Origin) == 0 {
 		return true
 	}
-	for _, o := range Options.Origin {
+	for _, o := range c.Origin {
 		if o == "*" || o == r.URL.Host {
 			return true
 		}
@@ -38,6 +50,22 @@ func AccessControlAllow(r *http.Request) bool {
 	return false
 }
 
+// upgrader 返回监听器使用的 Upgrader，未设置 CheckOrigin 时使用 Origin 检查
+func (c *Config) upgrader() *websocket.Upgrader {
+	u := c.Upgrader
+	if u.CheckOrigin == nil {
+		u.CheckOrigin = c.AccessControlAllow
+	}
+	return &u
+}
+
+func (c *Config) transform() transform {
+	if c != nil && c.Transform != nil {
+		return c.Transform
+	}
+	return Options.Transform
+}
+
 type transform interface {
 	ReadMessage(socket listener.Socket, msg message.Message, data []byte) error
 	WriteMessage(socket listener.Socket, msg message.Message, b *bytes.Buffer) (n int, err error)